@@ -0,0 +1,127 @@
+package cache
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+	"github.com/zohaib-a-ahmed/drivebox/pkg/auth"
+	"github.com/zohaib-a-ahmed/drivebox/pkg/cache"
+	"github.com/zohaib-a-ahmed/drivebox/pkg/drive"
+	"github.com/zohaib-a-ahmed/drivebox/pkg/progress"
+)
+
+// full rebuilds the cache from a complete listing instead of applying changes.
+var full bool
+
+func init() {
+	CacheCmd.AddCommand(refreshCmd, clearCmd, statsCmd)
+	refreshCmd.Flags().BoolVar(&full, "full", false, "Rebuild the cache from a full listing instead of applying recent changes")
+}
+
+var CacheCmd = &cobra.Command{
+	Use:   "cache",
+	Short: "Manage the local metadata cache",
+	Long: `Keep file metadata on disk so paths and searches resolve without walking Drive folder by folder.
+Run 'drivebox cache refresh' once to build the cache; afterwards every command keeps it up to date
+through the Drive Changes API. Each shared drive selected with --drive has its own cache. Use the
+global --no-cache flag to query Drive directly.`,
+}
+
+var refreshCmd = &cobra.Command{
+	Use:   "refresh",
+	Short: "Build the cache, or bring it up to date",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		// Work on the cache directly rather than through it
+		cache.Disabled = true
+		client, err := auth.CreateDriveClient()
+		if err != nil {
+			return fmt.Errorf("failed to create Google Drive service: %w", err)
+		}
+		ctx := cmd.Context()
+
+		c, err := cache.Load(client)
+		if err != nil && !full {
+			return err
+		}
+		if c == nil || full {
+			log.Println("Listing every file; this may take a while for large drives...")
+			if c, err = cache.Build(ctx, client); err != nil {
+				return err
+			}
+			log.Printf("Cached %d items.", len(c.Files))
+		} else {
+			n, err := c.Sync(ctx, client)
+			if err != nil {
+				return fmt.Errorf("failed to apply changes: %v; run 'drivebox cache refresh --full' to rebuild the cache", err)
+			}
+			log.Printf("Applied %d changes.", n)
+		}
+		return c.Save()
+	},
+}
+
+var clearCmd = &cobra.Command{
+	Use:   "clear",
+	Short: "Delete the cache for every drive",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := cache.Clear(); err != nil {
+			return fmt.Errorf("failed to clear the cache: %v", err)
+		}
+		log.Println("Cache cleared.")
+		return nil
+	},
+}
+
+var statsCmd = &cobra.Command{
+	Use:   "stats",
+	Short: "Show what the cache holds and when it was refreshed",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cache.Disabled = true
+		client, err := auth.CreateDriveClient()
+		if err != nil {
+			return fmt.Errorf("failed to create Google Drive service: %w", err)
+		}
+
+		c, err := cache.Load(client)
+		if err != nil {
+			return err
+		}
+		if c == nil {
+			fmt.Println("No cache has been built. Run 'drivebox cache refresh' to build one.")
+			return nil
+		}
+
+		var folders, files, trashed int
+		var size int64
+		for _, f := range c.Files {
+			switch {
+			case f.Trashed:
+				trashed++
+			case drive.IsFolder(f):
+				folders++
+			default:
+				files++
+				size += f.Size
+			}
+		}
+		var onDisk int64
+		if info, err := os.Stat(c.File()); err == nil {
+			onDisk = info.Size()
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintf(w, "Location:\t%s\n", c.File())
+		fmt.Fprintf(w, "Refreshed:\t%s\n", c.Refreshed.Local().Format("2006-01-02 15:04:05"))
+		fmt.Fprintf(w, "Folders:\t%d\n", folders)
+		fmt.Fprintf(w, "Files:\t%d (%s)\n", files, progress.FormatBytes(size))
+		fmt.Fprintf(w, "Trashed:\t%d\n", trashed)
+		fmt.Fprintf(w, "Cache size:\t%s\n", progress.FormatBytes(onDisk))
+		return w.Flush()
+	},
+}
@@ -0,0 +1,129 @@
+package cp
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/spf13/cobra"
+	"github.com/zohaib-a-ahmed/drivebox/pkg/auth"
+	"github.com/zohaib-a-ahmed/drivebox/pkg/drive"
+)
+
+var (
+	recursive bool
+	preserve  bool
+)
+
+func init() {
+	CpCmd.Flags().BoolVarP(&recursive, "recursive", "r", false, "Copy folders and everything inside them")
+	CpCmd.Flags().BoolVar(&preserve, "preserve", false, "Also copy descriptions and custom properties")
+}
+
+// copyFields are the fields needed to copy an item and, with --preserve, its metadata.
+var copyFields = []string{"id", "name", "mimeType", "parents", "description", "properties"}
+
+var CpCmd = &cobra.Command{
+	Use:   "cp [-r] <source>... <destination>",
+	Short: "Copy files and folders within Google Drive",
+	Long: `Copy files and folders server-side, without downloading and uploading them again. Sources and the
+destination are paths from the root, such as "/Work/report.pdf", or IDs written as id:<ID>.
+
+If the destination is an existing folder, every source is copied into it under its own name.
+Otherwise the destination names the copy of a single source. Folders need -r; they are recreated
+at the destination and their contents are copied one by one.`,
+	Args: cobra.MinimumNArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		client, err := auth.CreateDriveClient()
+		if err != nil {
+			return fmt.Errorf("failed to create Google Drive service: %w", err)
+		}
+		ctx := cmd.Context()
+
+		sources, dest := args[:len(args)-1], args[len(args)-1]
+		folder, newName, err := drive.Destination(ctx, client, dest, len(sources) > 1)
+		if err != nil {
+			return err
+		}
+
+		for _, src := range sources {
+			f, err := drive.Lookup(ctx, client, src, copyFields...)
+			if err != nil {
+				return err
+			}
+			name := f.Name
+			if newName != "" {
+				name = newName
+			}
+			if err := checkSource(ctx, client, f, folder, name); err != nil {
+				return fmt.Errorf("cannot copy %s: %v", src, err)
+			}
+			count, err := copyItem(ctx, client, f, folder.Id, name)
+			if err != nil {
+				return fmt.Errorf("failed to copy %s: %v", src, err)
+			}
+			log.Printf("Copied %s (%d item(s))", src, count)
+		}
+		return nil
+	},
+}
+
+// checkSource rejects copies that would be ambiguous or never finish.
+func checkSource(ctx context.Context, client drive.Client, f, folder *drive.File, name string) error {
+	if drive.IsFolder(f) {
+		if !recursive {
+			return fmt.Errorf("it is a folder; use -r to copy folders")
+		}
+		inside, err := drive.IsWithin(ctx, client, folder, f.Id)
+		if err != nil {
+			return err
+		}
+		if inside {
+			return fmt.Errorf("a folder cannot be copied into itself")
+		}
+	}
+	existing, err := drive.Child(ctx, client, folder.Id, name, "id")
+	if err != nil {
+		return err
+	}
+	if existing != nil {
+		return fmt.Errorf("an item named %q already exists in the destination folder", name)
+	}
+	return nil
+}
+
+// copyItem copies f into the folder parentID as name, recreating folders and
+// copying their contents. It returns the number of items created.
+func copyItem(ctx context.Context, client drive.Client, f *drive.File, parentID, name string) (int, error) {
+	meta := &drive.File{Name: name, Parents: []string{parentID}}
+	if preserve {
+		meta.Description = f.Description
+		meta.Properties = f.Properties
+	}
+
+	if !drive.IsFolder(f) {
+		if _, err := client.Copy(ctx, f.Id, meta, "id"); err != nil {
+			return 0, fmt.Errorf("%s: %v", f.Name, err)
+		}
+		return 1, nil
+	}
+
+	meta.MimeType = drive.FolderMimeType
+	newFolder, err := client.Create(ctx, meta, nil, drive.CreateOptions{Fields: []string{"id"}})
+	if err != nil {
+		return 0, fmt.Errorf("%s: %v", f.Name, err)
+	}
+	children, err := drive.Children(ctx, client, f.Id, copyFields...)
+	if err != nil {
+		return 0, err
+	}
+	count := 1
+	for _, child := range children {
+		n, err := copyItem(ctx, client, child, newFolder.Id, child.Name)
+		count += n
+		if err != nil {
+			return count, err
+		}
+	}
+	return count, nil
+}
@@ -0,0 +1,52 @@
+package drives
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+	"github.com/zohaib-a-ahmed/drivebox/pkg/auth"
+	"github.com/zohaib-a-ahmed/drivebox/pkg/format"
+)
+
+func init() {
+	DrivesCmd.AddCommand(listCmd)
+}
+
+var DrivesCmd = &cobra.Command{
+	Use:   "drives",
+	Short: "Work with shared drives",
+	Long: `List the shared drives you are a member of. Pass a drive's name or ID to the global --drive flag
+to run any command inside it, e.g. 'drivebox --drive Engineering search report'.`,
+}
+
+var listCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the shared drives you are a member of",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		// Listing drives never happens inside one
+		auth.SharedDrive = ""
+		client, err := auth.CreateDriveClient()
+		if err != nil {
+			return fmt.Errorf("failed to create Google Drive service: %w", err)
+		}
+
+		drives, err := client.Drives(cmd.Context())
+		if err != nil {
+			return fmt.Errorf("failed to list shared drives: %v", err)
+		}
+		if len(drives) == 0 {
+			fmt.Println("You are not a member of any shared drives.")
+			return nil
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "NAME\tCREATED\tID")
+		for _, d := range drives {
+			fmt.Fprintf(w, "%s\t%s\t%s\n", d.Name, format.Time(d.CreatedTime, format.Date), d.Id)
+		}
+		return w.Flush()
+	},
+}
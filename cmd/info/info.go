@@ -0,0 +1,147 @@
+package info
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+	"github.com/zohaib-a-ahmed/drivebox/pkg/auth"
+	"github.com/zohaib-a-ahmed/drivebox/pkg/drive"
+	"github.com/zohaib-a-ahmed/drivebox/pkg/format"
+	"github.com/zohaib-a-ahmed/drivebox/pkg/plan"
+	"github.com/zohaib-a-ahmed/drivebox/pkg/progress"
+)
+
+var asJSON bool
+
+// infoFields are the file fields shown by info.
+var infoFields = []string{
+	"id", "name", "mimeType", "description", "size", "md5Checksum", "sha1Checksum", "sha256Checksum",
+	"createdTime", "modifiedTime", "owners", "lastModifyingUser", "permissions", "shared",
+	"webViewLink", "parents", "starred", "trashed", "properties", "appProperties",
+}
+
+func init() {
+	InfoCmd.Flags().BoolVar(&asJSON, "json", false, "Print the metadata as JSON")
+}
+
+var InfoCmd = &cobra.Command{
+	Use:     "info <path_or_id>",
+	Aliases: []string{"stat"},
+	Short:   "Show the full metadata of a file or folder",
+	Long: `Show everything Drive knows about an item: its paths, ID, size, type, checksums, times, owners,
+sharing permissions, links and properties. The item is a path from the root, such as
+"/Work/report.pdf", or an ID written as id:<ID>.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if asJSON {
+			plan.UseStderr()
+		}
+		client, err := auth.CreateDriveClient()
+		if err != nil {
+			return fmt.Errorf("failed to create Google Drive service: %w", err)
+		}
+		ctx := cmd.Context()
+
+		f, err := drive.Lookup(ctx, client, args[0], infoFields...)
+		if err != nil {
+			return err
+		}
+		paths, err := drive.NewPathFinder(client).Paths(ctx, f)
+		if err != nil {
+			return fmt.Errorf("failed to resolve paths of %s: %v", f.Name, err)
+		}
+
+		if asJSON {
+			return printJSON(f, paths)
+		}
+		printText(f, paths)
+		return nil
+	},
+}
+
+// printJSON prints the file resource as returned by the API, plus its paths.
+func printJSON(f *drive.File, paths []string) error {
+	data, err := f.MarshalJSON()
+	if err != nil {
+		return err
+	}
+	var out map[string]any
+	if err := json.Unmarshal(data, &out); err != nil {
+		return err
+	}
+	out["paths"] = paths
+
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(out)
+}
+
+func printText(f *drive.File, paths []string) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	row := func(label, value string) {
+		if value != "" {
+			fmt.Fprintf(w, "%s:\t%s\n", label, value)
+		}
+	}
+
+	row("Name", f.Name)
+	for _, p := range paths {
+		row("Path", p)
+	}
+	row("ID", f.Id)
+	row("Type", f.MimeType)
+	if !drive.IsFolder(f) && !drive.IsWorkspace(f) {
+		row("Size", fmt.Sprintf("%s (%d bytes)", progress.FormatBytes(f.Size), f.Size))
+	}
+	row("Description", f.Description)
+	row("MD5", f.Md5Checksum)
+	row("SHA-1", f.Sha1Checksum)
+	row("SHA-256", f.Sha256Checksum)
+	row("Created", format.Time(f.CreatedTime, format.Second+" MST"))
+	row("Modified", format.Time(f.ModifiedTime, format.Second+" MST"))
+	if f.LastModifyingUser != nil {
+		row("Modified by", formatUser(f.LastModifyingUser.DisplayName, f.LastModifyingUser.EmailAddress))
+	}
+	for _, o := range f.Owners {
+		row("Owner", formatUser(o.DisplayName, o.EmailAddress))
+	}
+	row("Parents", strings.Join(f.Parents, ", "))
+	row("Starred", fmt.Sprint(f.Starred))
+	row("Trashed", fmt.Sprint(f.Trashed))
+	row("Shared", fmt.Sprint(f.Shared))
+	for _, p := range f.Permissions {
+		row("Permission", p.Role+": "+drive.Grantee(p))
+	}
+	row("Link", f.WebViewLink)
+	for _, k := range sortedKeys(f.Properties) {
+		row("Property", k+" = "+f.Properties[k])
+	}
+	for _, k := range sortedKeys(f.AppProperties) {
+		row("App property", k+" = "+f.AppProperties[k])
+	}
+	w.Flush()
+}
+
+func formatUser(name, email string) string {
+	switch {
+	case email == "":
+		return name
+	case name == "":
+		return email
+	}
+	return fmt.Sprintf("%s <%s>", name, email)
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,45 @@
+package mkdir
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/spf13/cobra"
+	"github.com/zohaib-a-ahmed/drivebox/pkg/auth"
+	"github.com/zohaib-a-ahmed/drivebox/pkg/drive"
+)
+
+var parents bool
+
+func init() {
+	MkdirCmd.Flags().BoolVarP(&parents, "parents", "p", false, "Create missing parent folders and succeed if the folder already exists")
+}
+
+var MkdirCmd = &cobra.Command{
+	Use:   "mkdir [-p] <path>...",
+	Short: "Create folders in Google Drive",
+	Long: `Create a folder at each given path, such as "/Backups/2024/March". The parent folder must
+already exist unless -p is given. Creation fails if the parent already holds an item with the same name.`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		client, err := auth.CreateDriveClient()
+		if err != nil {
+			return fmt.Errorf("failed to create Google Drive service: %w", err)
+		}
+		ctx := cmd.Context()
+
+		for _, p := range args {
+			var folder *drive.File
+			if parents {
+				folder, err = drive.MkdirAll(ctx, client, p)
+			} else {
+				folder, err = drive.Mkdir(ctx, client, p)
+			}
+			if err != nil {
+				return fmt.Errorf("failed to create folder: %v", err)
+			}
+			log.Printf("Folder %s ready (ID: %s)", p, folder.Id)
+		}
+		return nil
+	},
+}
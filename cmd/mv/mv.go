@@ -0,0 +1,103 @@
+package mv
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/zohaib-a-ahmed/drivebox/pkg/auth"
+	"github.com/zohaib-a-ahmed/drivebox/pkg/drive"
+)
+
+var MvCmd = &cobra.Command{
+	Use:   "mv <source>... <destination>",
+	Short: "Move or rename files and folders in Google Drive",
+	Long: `Move files and folders in your Google Drive. Sources and the destination are paths from the
+root, such as "/Work/report.pdf", or IDs written as id:<ID>.
+
+If the destination is an existing folder, every source is moved into it. Otherwise the destination
+names a new item: a single source is moved into its parent folder and renamed. Existing items are
+never overwritten. Use the global --dry-run flag to preview the moves.`,
+	Args: cobra.MinimumNArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		client, err := auth.CreateDriveClient()
+		if err != nil {
+			return fmt.Errorf("failed to create Google Drive service: %w", err)
+		}
+		ctx := cmd.Context()
+
+		sources, dest := args[:len(args)-1], args[len(args)-1]
+		folder, newName, err := drive.Destination(ctx, client, dest, len(sources) > 1)
+		if err != nil {
+			return err
+		}
+
+		for _, src := range sources {
+			if err := move(ctx, client, src, folder, newName); err != nil {
+				return fmt.Errorf("failed to move %s: %v", src, err)
+			}
+		}
+		return nil
+	},
+}
+
+// move moves the item src into folder, renaming it to newName if set.
+func move(ctx context.Context, client drive.Client, src string, folder *drive.File, newName string) error {
+	f, err := drive.Lookup(ctx, client, src, "id", "name", "mimeType", "parents")
+	if err != nil {
+		return err
+	}
+	name := f.Name
+	if newName != "" {
+		name = newName
+	}
+
+	if drive.IsFolder(f) {
+		inside, err := drive.IsWithin(ctx, client, folder, f.Id)
+		if err != nil {
+			return err
+		}
+		if inside {
+			return fmt.Errorf("cannot move a folder into itself")
+		}
+	}
+
+	alreadyThere := len(f.Parents) == 1 && f.Parents[0] == folder.Id
+	if alreadyThere && name == f.Name {
+		fmt.Printf("%s is already in place\n", src)
+		return nil
+	}
+	existing, err := drive.Child(ctx, client, folder.Id, name, "id")
+	if err != nil {
+		return err
+	}
+	if existing != nil && existing.Id != f.Id {
+		return fmt.Errorf("an item named %q already exists in the destination folder", name)
+	}
+
+	// Move and rename in one request so a failure cannot leave only one done
+	update := &drive.File{}
+	opts := drive.UpdateOptions{Fields: []string{"id"}}
+	if name != f.Name {
+		update.Name = name
+	}
+	if !alreadyThere {
+		opts.AddParents, opts.RemoveParents = []string{folder.Id}, f.Parents
+	}
+	if _, err := client.Update(ctx, f.Id, update, opts); err != nil {
+		return err
+	}
+	log.Printf("Moved %s to %s", src, destPath(ctx, client, folder, name))
+	return nil
+}
+
+// destPath returns the path an item called name has inside folder, for messages.
+func destPath(ctx context.Context, client drive.Client, folder *drive.File, name string) string {
+	p, err := drive.NewPathFinder(client).Paths(ctx, folder)
+	if err != nil || len(p) == 0 {
+		return name
+	}
+	return strings.TrimSuffix(p[0], "/") + "/" + name
+}
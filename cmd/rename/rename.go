@@ -0,0 +1,57 @@
+package rename
+
+import (
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/zohaib-a-ahmed/drivebox/pkg/auth"
+	"github.com/zohaib-a-ahmed/drivebox/pkg/drive"
+)
+
+var RenameCmd = &cobra.Command{
+	Use:   "rename <path_or_id> <new_name>",
+	Short: "Rename a file or folder in Google Drive",
+	Long: `Rename a file or folder in place. The item is a path from the root, such as "/Work/report.pdf",
+or an ID written as id:<ID>. Use 'drivebox mv' to move items between folders.`,
+	Args: cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ref, newName := args[0], args[1]
+		if newName == "" || strings.Contains(newName, "/") {
+			return fmt.Errorf("invalid name %q: names cannot be empty or contain '/'", newName)
+		}
+
+		client, err := auth.CreateDriveClient()
+		if err != nil {
+			return fmt.Errorf("failed to create Google Drive service: %w", err)
+		}
+		ctx := cmd.Context()
+
+		f, err := drive.Lookup(ctx, client, ref, "id", "name", "mimeType", "parents")
+		if err != nil {
+			return err
+		}
+		if f.Name == newName {
+			fmt.Printf("%s is already named %q\n", ref, newName)
+			return nil
+		}
+
+		// Keep paths unambiguous: refuse names already taken next to the item
+		for _, parentID := range f.Parents {
+			existing, err := drive.Child(ctx, client, parentID, newName, "id")
+			if err != nil {
+				return err
+			}
+			if existing != nil {
+				return fmt.Errorf("an item named %q already exists in the same folder", newName)
+			}
+		}
+
+		if _, err := client.Update(ctx, f.Id, &drive.File{Name: newName}, drive.UpdateOptions{Fields: []string{"id"}}); err != nil {
+			return fmt.Errorf("failed to rename %s: %v", ref, err)
+		}
+		log.Printf("Renamed %s to %s", f.Name, newName)
+		return nil
+	},
+}
@@ -0,0 +1,85 @@
+package rm
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/spf13/cobra"
+	"github.com/zohaib-a-ahmed/drivebox/pkg/auth"
+	"github.com/zohaib-a-ahmed/drivebox/pkg/drive"
+	"github.com/zohaib-a-ahmed/drivebox/pkg/picker"
+	"github.com/zohaib-a-ahmed/drivebox/pkg/plan"
+)
+
+var (
+	recursive bool
+	permanent bool
+	yes       bool
+)
+
+func init() {
+	RmCmd.Flags().BoolVarP(&recursive, "recursive", "r", false, "Remove folders and everything inside them")
+	RmCmd.Flags().BoolVar(&permanent, "permanent", false, "Delete permanently instead of moving to the trash")
+	RmCmd.Flags().BoolVarP(&yes, "yes", "y", false, "Do not ask for confirmation before deleting permanently")
+}
+
+var RmCmd = &cobra.Command{
+	Use:   "rm [-r] [--permanent] <path_or_id>...",
+	Short: "Move files and folders to the Google Drive trash",
+	Long: `Move files and folders to the trash, from where 'drivebox trash restore' can bring them back.
+Items are paths from the root, such as "/Work/report.pdf", or IDs written as id:<ID>. Folders need -r.
+
+With --permanent, items are deleted immediately and cannot be recovered. You are asked to confirm
+first unless --yes is given.`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		client, err := auth.CreateDriveClient()
+		if err != nil {
+			return fmt.Errorf("failed to create Google Drive service: %w", err)
+		}
+		ctx := cmd.Context()
+
+		root, err := client.Get(ctx, drive.Root(client), "id")
+		if err != nil {
+			return fmt.Errorf("failed to look up the root folder: %v", err)
+		}
+
+		// Resolve everything first so a bad argument removes nothing
+		var files []*drive.File
+		for _, ref := range args {
+			f, err := drive.Lookup(ctx, client, ref, "id", "name", "mimeType", "parents")
+			if err != nil {
+				return err
+			}
+			if f.Id == root.Id {
+				return fmt.Errorf("%s: refusing to remove the root folder", ref)
+			}
+			if drive.IsFolder(f) && !recursive {
+				return fmt.Errorf("%s is a folder; use -r to remove folders", ref)
+			}
+			files = append(files, f)
+		}
+
+		if permanent && !yes {
+			if !plan.DryRun && !picker.Confirm(fmt.Sprintf("Permanently delete %d item(s)? This cannot be undone.", len(files))) {
+				fmt.Println("Nothing deleted.")
+				return nil
+			}
+		}
+
+		for i, f := range files {
+			if permanent {
+				if err := client.Delete(ctx, f.Id); err != nil {
+					return fmt.Errorf("failed to delete %s: %v", args[i], err)
+				}
+				log.Printf("Deleted %s", args[i])
+				continue
+			}
+			if _, err := drive.Trash(ctx, client, f.Id); err != nil {
+				return fmt.Errorf("failed to trash %s: %v", args[i], err)
+			}
+			log.Printf("Moved %s to the trash", args[i])
+		}
+		return nil
+	},
+}
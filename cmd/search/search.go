@@ -0,0 +1,143 @@
+package search
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+	"github.com/zohaib-a-ahmed/drivebox/pkg/auth"
+	"github.com/zohaib-a-ahmed/drivebox/pkg/drive"
+	"github.com/zohaib-a-ahmed/drivebox/pkg/format"
+	"github.com/zohaib-a-ahmed/drivebox/pkg/query"
+)
+
+var (
+	name           string
+	fullText       string
+	fileType       string
+	modifiedAfter  string
+	modifiedBefore string
+	owner          string
+	in             string
+	trashed        bool
+	starred        bool
+	limit          int
+)
+
+// types maps the values accepted by --type to the query that selects them.
+var types = map[string]query.Query{
+	"pdf":    query.MimeType("application/pdf"),
+	"folder": query.Folder(),
+	"doc":    query.MimeType("application/vnd.google-apps.document"),
+	"sheet":  query.MimeType("application/vnd.google-apps.spreadsheet"),
+	"image":  query.MimeTypeContains("image/"),
+}
+
+func init() {
+	SearchCmd.Flags().StringVar(&name, "name", "", "Match items whose name contains this text")
+	SearchCmd.Flags().StringVar(&fullText, "fulltext", "", "Match items whose name, description or content contains this text")
+	SearchCmd.Flags().StringVar(&fileType, "type", "", "Match items of a type: pdf, folder, doc, sheet or image")
+	SearchCmd.Flags().StringVar(&modifiedAfter, "modified-after", "", "Match items modified after a date (YYYY-MM-DD or RFC 3339)")
+	SearchCmd.Flags().StringVar(&modifiedBefore, "modified-before", "", "Match items modified before a date (YYYY-MM-DD or RFC 3339)")
+	SearchCmd.Flags().StringVar(&owner, "owner", "", "Match items owned by this email address")
+	SearchCmd.Flags().StringVar(&in, "in", "", "Only search directly inside this folder (path or id:<ID>)")
+	SearchCmd.Flags().BoolVar(&trashed, "trashed", false, "Search the trash instead of live items")
+	SearchCmd.Flags().BoolVar(&starred, "starred", false, "Only match starred items")
+	SearchCmd.Flags().IntVar(&limit, "limit", 100, "Maximum number of results; 0 for no limit")
+}
+
+var SearchCmd = &cobra.Command{
+	Use:   "search [name]",
+	Short: "Search for files and folders in Google Drive",
+	Long: `Search your Google Drive with filters on name, content, type, dates, owner and location.
+Results are printed with their full paths. A positional argument is shorthand for --name.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if len(args) == 1 {
+			name = args[0]
+		}
+
+		client, err := auth.CreateDriveClient()
+		if err != nil {
+			return fmt.Errorf("failed to create Google Drive service: %w", err)
+		}
+		ctx := cmd.Context()
+
+		clauses := []query.Query{query.Trashed(trashed)}
+		if name != "" {
+			clauses = append(clauses, query.NameContains(name))
+		}
+		if fullText != "" {
+			clauses = append(clauses, query.FullText(fullText))
+		}
+		if fileType != "" {
+			q, ok := types[strings.ToLower(fileType)]
+			if !ok {
+				return fmt.Errorf("unknown type %q: expected pdf, folder, doc, sheet or image", fileType)
+			}
+			clauses = append(clauses, q)
+		}
+		if modifiedAfter != "" {
+			t, err := format.ParseDate(modifiedAfter)
+			if err != nil {
+				return err
+			}
+			clauses = append(clauses, query.ModifiedAfter(t))
+		}
+		if modifiedBefore != "" {
+			t, err := format.ParseDate(modifiedBefore)
+			if err != nil {
+				return err
+			}
+			clauses = append(clauses, query.ModifiedBefore(t))
+		}
+		if owner != "" {
+			clauses = append(clauses, query.Owner(owner))
+		}
+		if starred {
+			clauses = append(clauses, query.Starred(true))
+		}
+		if in != "" {
+			folder, err := drive.Lookup(ctx, client, in)
+			if err != nil {
+				return err
+			}
+			if !drive.IsFolder(folder) {
+				return fmt.Errorf("%s is not a folder", in)
+			}
+			clauses = append(clauses, query.InParents(folder.Id))
+		}
+
+		files, err := client.List(ctx, drive.ListOptions{
+			Query:   query.And(clauses...).String(),
+			Fields:  []string{"id", "name", "mimeType", "parents", "size", "modifiedTime"},
+			OrderBy: "folder,name",
+			Limit:   limit,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to search files: %v", err)
+		}
+		if len(files) == 0 {
+			fmt.Println("No files found.")
+			return nil
+		}
+
+		paths := drive.NewPathFinder(client)
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "NAME\tPATH\tSIZE\tMODIFIED\tID")
+		for _, f := range files {
+			p, err := paths.Path(ctx, f)
+			if err != nil {
+				return fmt.Errorf("failed to resolve path of %s: %v", f.Name, err)
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", f.Name, p, format.Size(f), format.Time(f.ModifiedTime, format.Minute), f.Id)
+		}
+		w.Flush()
+		if limit > 0 && len(files) == limit {
+			fmt.Printf("Showing the first %d results; use --limit to see more.\n", limit)
+		}
+		return nil
+	},
+}
@@ -0,0 +1,219 @@
+package share
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/zohaib-a-ahmed/drivebox/pkg/auth"
+	"github.com/zohaib-a-ahmed/drivebox/pkg/drive"
+	"github.com/zohaib-a-ahmed/drivebox/pkg/format"
+)
+
+var (
+	users    []string
+	groups   []string
+	domain   string
+	anyone   bool
+	role     string
+	expires  string
+	notify   bool
+	noNotify bool
+	message  string
+)
+
+// roles are the roles that can be granted with share.
+var roles = []string{"reader", "commenter", "writer"}
+
+func init() {
+	ShareCmd.AddCommand(listCmd, revokeCmd)
+	ShareCmd.Flags().StringSliceVar(&users, "user", nil, "Share with a user by email address (repeatable)")
+	ShareCmd.Flags().StringSliceVar(&groups, "group", nil, "Share with a Google group by email address (repeatable)")
+	ShareCmd.Flags().StringVar(&domain, "domain", "", "Share with everyone in a Google Workspace domain")
+	ShareCmd.Flags().BoolVar(&anyone, "anyone", false, "Share with anyone who has the link")
+	ShareCmd.Flags().StringVar(&role, "role", "reader", "Role to grant: reader, commenter or writer")
+	ShareCmd.Flags().StringVar(&expires, "expires", "", "Remove user and group access after a date (YYYY-MM-DD or RFC 3339)")
+	ShareCmd.Flags().BoolVar(&notify, "notify", true, "Email users and groups about the new access")
+	ShareCmd.Flags().BoolVar(&noNotify, "no-notify", false, "Do not email users and groups")
+	ShareCmd.Flags().StringVar(&message, "message", "", "Message to include in the notification email")
+	ShareCmd.MarkFlagsMutuallyExclusive("notify", "no-notify")
+}
+
+var ShareCmd = &cobra.Command{
+	Use:   "share <path_or_id>",
+	Short: "Share a file or folder",
+	Long: `Grant access to a file or folder to users, groups, a domain or anyone with the link. The item is
+a path from the root, such as "/Work/report.pdf", or an ID written as id:<ID>.
+
+Granting access to someone who already has it changes their role. Use 'drivebox share list' to see
+who has access and 'drivebox share revoke' to remove it.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		perms, err := permissions()
+		if err != nil {
+			return err
+		}
+
+		client, err := auth.CreateDriveClient()
+		if err != nil {
+			return fmt.Errorf("failed to create Google Drive service: %w", err)
+		}
+		ctx := cmd.Context()
+
+		f, err := drive.Lookup(ctx, client, args[0], "id", "name", "webViewLink")
+		if err != nil {
+			return err
+		}
+		opts := drive.ShareOptions{Notify: notify && !noNotify, Message: message}
+		for _, p := range perms {
+			if _, err := client.Share(ctx, f.Id, p, opts); err != nil {
+				return fmt.Errorf("failed to share %s with %s: %v", args[0], drive.Grantee(p), err)
+			}
+			log.Printf("Shared %s with %s as %s", f.Name, drive.Grantee(p), p.Role)
+		}
+		if f.WebViewLink != "" {
+			fmt.Println("Link:", f.WebViewLink)
+		}
+		return nil
+	},
+}
+
+// permissions builds the permissions requested on the command line.
+func permissions() ([]*drive.Permission, error) {
+	if !validRole(role) {
+		return nil, fmt.Errorf("invalid role %q: expected %s", role, strings.Join(roles, ", "))
+	}
+	var expiration string
+	if expires != "" {
+		t, err := format.ParseDate(expires)
+		if err != nil {
+			return nil, err
+		}
+		if !t.After(time.Now()) {
+			return nil, fmt.Errorf("expiration date %s is in the past", expires)
+		}
+		expiration = t.UTC().Format(time.RFC3339)
+	}
+
+	var perms []*drive.Permission
+	for _, email := range users {
+		perms = append(perms, &drive.Permission{Type: "user", Role: role, EmailAddress: email, ExpirationTime: expiration})
+	}
+	for _, email := range groups {
+		perms = append(perms, &drive.Permission{Type: "group", Role: role, EmailAddress: email, ExpirationTime: expiration})
+	}
+	if expiration != "" && (domain != "" || anyone) {
+		return nil, fmt.Errorf("--expires only applies to --user and --group")
+	}
+	if domain != "" {
+		perms = append(perms, &drive.Permission{Type: "domain", Role: role, Domain: domain})
+	}
+	if anyone {
+		perms = append(perms, &drive.Permission{Type: "anyone", Role: role})
+	}
+	if len(perms) == 0 {
+		return nil, fmt.Errorf("nobody to share with: use --user, --group, --domain or --anyone")
+	}
+	return perms, nil
+}
+
+// validRole reports whether role can be granted with share.
+func validRole(role string) bool {
+	for _, r := range roles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+var listCmd = &cobra.Command{
+	Use:   "list <path_or_id>",
+	Short: "List who has access to a file or folder",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		client, err := auth.CreateDriveClient()
+		if err != nil {
+			return fmt.Errorf("failed to create Google Drive service: %w", err)
+		}
+		ctx := cmd.Context()
+
+		f, err := drive.Lookup(ctx, client, args[0], "id")
+		if err != nil {
+			return err
+		}
+		perms, err := client.Permissions(ctx, f.Id)
+		if err != nil {
+			return fmt.Errorf("failed to list permissions: %v", err)
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "ROLE\tWHO\tEXPIRES\tPERMISSION ID")
+		for _, p := range perms {
+			expiry := "-"
+			if p.ExpirationTime != "" {
+				expiry = format.Time(p.ExpirationTime, format.Minute)
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", p.Role, drive.Grantee(p), expiry, p.Id)
+		}
+		return w.Flush()
+	},
+}
+
+var revokeCmd = &cobra.Command{
+	Use:   "revoke <path_or_id> <who>...",
+	Short: "Remove access to a file or folder",
+	Long: `Remove access granted with 'drivebox share'. Each <who> is an email address, a domain, "anyone",
+or a permission ID as shown by 'drivebox share list'. The owner's access cannot be removed.`,
+	Args: cobra.MinimumNArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		client, err := auth.CreateDriveClient()
+		if err != nil {
+			return fmt.Errorf("failed to create Google Drive service: %w", err)
+		}
+		ctx := cmd.Context()
+
+		f, err := drive.Lookup(ctx, client, args[0], "id", "name")
+		if err != nil {
+			return err
+		}
+		perms, err := client.Permissions(ctx, f.Id)
+		if err != nil {
+			return fmt.Errorf("failed to list permissions: %v", err)
+		}
+
+		for _, who := range args[1:] {
+			p := findPermission(perms, who)
+			if p == nil {
+				return fmt.Errorf("no access to %s matches %q", f.Name, who)
+			}
+			if p.Role == "owner" {
+				return fmt.Errorf("cannot revoke the owner's access to %s", f.Name)
+			}
+			if err := client.Unshare(ctx, f.Id, p.Id); err != nil {
+				return fmt.Errorf("failed to revoke access for %s: %v", drive.Grantee(p), err)
+			}
+			log.Printf("Revoked access to %s for %s", f.Name, drive.Grantee(p))
+		}
+		return nil
+	},
+}
+
+// findPermission returns the permission matching who by ID, email address,
+// domain or "anyone", or nil if there is none.
+func findPermission(perms []*drive.Permission, who string) *drive.Permission {
+	for _, p := range perms {
+		switch {
+		case p.Id == who,
+			p.Type == "anyone" && who == "anyone",
+			p.EmailAddress != "" && strings.EqualFold(p.EmailAddress, who),
+			p.Type == "domain" && strings.EqualFold(p.Domain, who):
+			return p
+		}
+	}
+	return nil
+}
@@ -0,0 +1,188 @@
+package trash
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+	"github.com/zohaib-a-ahmed/drivebox/pkg/auth"
+	"github.com/zohaib-a-ahmed/drivebox/pkg/drive"
+	"github.com/zohaib-a-ahmed/drivebox/pkg/format"
+	"github.com/zohaib-a-ahmed/drivebox/pkg/picker"
+	"github.com/zohaib-a-ahmed/drivebox/pkg/plan"
+	"github.com/zohaib-a-ahmed/drivebox/pkg/query"
+)
+
+var yes bool
+
+// trashFields are fetched for trashed items.
+var trashFields = []string{"id", "name", "mimeType", "parents", "size", "trashedTime", "explicitlyTrashed"}
+
+func init() {
+	TrashCmd.AddCommand(listCmd, restoreCmd, emptyCmd)
+	emptyCmd.Flags().BoolVarP(&yes, "yes", "y", false, "Do not ask for confirmation")
+}
+
+var TrashCmd = &cobra.Command{
+	Use:   "trash",
+	Short: "List, restore and empty the Google Drive trash",
+	Long:  `Manage items removed with 'drivebox rm'. Trashed items can be restored until the trash is emptied.`,
+}
+
+var listCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List items in the trash",
+	Long: `List the items that were moved to the trash, with the path they were removed from.
+Items inside a trashed folder are restored with it and are not listed separately.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		client, err := auth.CreateDriveClient()
+		if err != nil {
+			return fmt.Errorf("failed to create Google Drive service: %w", err)
+		}
+		ctx := cmd.Context()
+
+		files, err := trashed(ctx, client, query.Query{})
+		if err != nil {
+			return err
+		}
+		if len(files) == 0 {
+			fmt.Println("The trash is empty.")
+			return nil
+		}
+
+		paths := drive.NewPathFinder(client)
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "NAME\tORIGINAL PATH\tSIZE\tTRASHED\tID")
+		for _, f := range files {
+			p, err := paths.Path(ctx, f)
+			if err != nil {
+				return fmt.Errorf("failed to resolve path of %s: %v", f.Name, err)
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", f.Name, p, format.Size(f), format.Time(f.TrashedTime, format.Minute), f.Id)
+		}
+		return w.Flush()
+	},
+}
+
+var restoreCmd = &cobra.Command{
+	Use:   "restore <name_path_or_id>...",
+	Short: "Restore items from the trash",
+	Long: `Restore trashed items to where they were removed from. Items are given by name, by the path
+they had before being trashed, such as "/Work/report.pdf", or by ID written as id:<ID>.`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		client, err := auth.CreateDriveClient()
+		if err != nil {
+			return fmt.Errorf("failed to create Google Drive service: %w", err)
+		}
+		ctx := cmd.Context()
+
+		for _, ref := range args {
+			f, err := findTrashed(ctx, client, ref)
+			if err != nil {
+				return err
+			}
+			if _, err := drive.Restore(ctx, client, f.Id); err != nil {
+				return fmt.Errorf("failed to restore %s: %v", ref, err)
+			}
+			log.Printf("Restored %s", ref)
+		}
+		return nil
+	},
+}
+
+var emptyCmd = &cobra.Command{
+	Use:   "empty",
+	Short: "Permanently delete everything in the trash",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		client, err := auth.CreateDriveClient()
+		if err != nil {
+			return fmt.Errorf("failed to create Google Drive service: %w", err)
+		}
+		ctx := cmd.Context()
+
+		if !yes && !plan.DryRun && !picker.Confirm("Permanently delete everything in the trash? This cannot be undone.") {
+			fmt.Println("Nothing deleted.")
+			return nil
+		}
+		if err := client.EmptyTrash(ctx, ""); err != nil {
+			return fmt.Errorf("failed to empty the trash: %v", err)
+		}
+		log.Println("Trash emptied.")
+		return nil
+	},
+}
+
+// trashed lists the items that were trashed directly, skipping those that
+// are only in the trash because a folder containing them was trashed.
+func trashed(ctx context.Context, client drive.Client, q query.Query) ([]*drive.File, error) {
+	files, err := client.List(ctx, drive.ListOptions{
+		Query:   query.And(query.Trashed(true), q).String(),
+		Fields:  trashFields,
+		OrderBy: "folder,name",
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list the trash: %v", err)
+	}
+	var out []*drive.File
+	for _, f := range files {
+		if f.ExplicitlyTrashed {
+			out = append(out, f)
+		}
+	}
+	return out, nil
+}
+
+// findTrashed returns the trashed item referenced by ref. Names and paths
+// must match exactly one trashed item.
+func findTrashed(ctx context.Context, client drive.Client, ref string) (*drive.File, error) {
+	if id, ok := strings.CutPrefix(ref, drive.IDPrefix); ok {
+		f, err := client.Get(ctx, id, trashFields...)
+		if err != nil {
+			return nil, err
+		}
+		if !f.Trashed {
+			return nil, fmt.Errorf("%s is not in the trash", ref)
+		}
+		return f, nil
+	}
+
+	names := drive.SplitPath(ref)
+	if len(names) == 0 {
+		return nil, fmt.Errorf("%q does not name an item", ref)
+	}
+	candidates, err := trashed(ctx, client, query.Name(names[len(names)-1]))
+	if err != nil {
+		return nil, err
+	}
+	if strings.Contains(ref, "/") {
+		// Compare the path each candidate had before it was trashed
+		paths := drive.NewPathFinder(client)
+		want := "/" + strings.Join(names, "/")
+		var matched []*drive.File
+		for _, f := range candidates {
+			p, err := paths.Path(ctx, f)
+			if err != nil {
+				return nil, err
+			}
+			if p == want {
+				matched = append(matched, f)
+			}
+		}
+		candidates = matched
+	}
+
+	switch len(candidates) {
+	case 0:
+		return nil, fmt.Errorf("%s: not found in the trash", ref)
+	case 1:
+		return candidates[0], nil
+	}
+	return nil, fmt.Errorf("%q matches %d trashed items; use 'drivebox trash list' and restore by %s<ID>", ref, len(candidates), drive.IDPrefix)
+}
@@ -0,0 +1,121 @@
+package unload
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+
+	"github.com/zohaib-a-ahmed/drivebox/pkg/drive"
+	"github.com/zohaib-a-ahmed/drivebox/pkg/ignore"
+	"github.com/zohaib-a-ahmed/drivebox/pkg/plan"
+)
+
+var (
+	// recursive downloads a folder with everything inside it.
+	recursive bool
+	// include and exclude filter the files of recursive downloads.
+	include []string
+	exclude []string
+)
+
+func init() {
+	UnloadCmd.Flags().BoolVarP(&recursive, "recursive", "r", false, "Download the folder at the given path or id:<ID> with everything inside it")
+	UnloadCmd.Flags().StringSliceVar(&include, "include", nil, "Only download files matching these glob patterns, e.g. '**/*.pdf' (recursive downloads)")
+	UnloadCmd.Flags().StringSliceVar(&exclude, "exclude", nil, "Skip files and folders matching these gitignore patterns, e.g. build/ (recursive downloads)")
+}
+
+// treeStats counts the outcome of a recursive download.
+type treeStats struct {
+	downloaded, skipped, failed int
+}
+
+// downloadTree downloads the Drive folder referenced by ref into destination,
+// recreating its folders. Items matched by --include, --exclude or a
+// .driveboxignore file inside the Drive folders are skipped.
+func downloadTree(ctx context.Context, client drive.Client, ref, destination string) error {
+	folder, err := drive.Lookup(ctx, client, ref, "id", "name", "mimeType")
+	if err != nil {
+		return fmt.Errorf("%s: %v", ref, err)
+	}
+	if !drive.IsFolder(folder) {
+		return fmt.Errorf("%s is not a folder; download files without -r", ref)
+	}
+	matcher, err := ignore.New(include, exclude)
+	if err != nil {
+		return err
+	}
+
+	var stats treeStats
+	localDir := filepath.Join(destination, sanitizeFileName(folder.Name))
+	if err := downloadDir(ctx, client, folder.Id, localDir, ".", matcher, &stats); err != nil {
+		return err
+	}
+	log.Printf("Downloaded %d files to %s (%d skipped, %d failed)", stats.downloaded, localDir, stats.skipped, stats.failed)
+	if stats.failed > 0 {
+		return fmt.Errorf("%d files failed to download", stats.failed)
+	}
+	return nil
+}
+
+// downloadDir downloads the contents of the Drive folder id into localDir.
+// rel is the folder's slash-separated path from the root of the download.
+func downloadDir(ctx context.Context, client drive.Client, id, localDir, rel string, matcher *ignore.Matcher, stats *treeStats) error {
+	if !plan.DryRun {
+		if err := os.MkdirAll(localDir, 0755); err != nil {
+			return fmt.Errorf("failed to create folder: %v", err)
+		}
+	}
+	children, err := drive.Children(ctx, client, id, "id", "name", "mimeType")
+	if err != nil {
+		return fmt.Errorf("failed to list %s: %v", rel, err)
+	}
+
+	// Patterns in this folder's ignore file apply to it and everything below
+	m := matcher.Clone()
+	for _, c := range children {
+		if c.Name == ignore.FileName && !drive.IsFolder(c) {
+			if err := loadIgnoreFile(ctx, client, c.Id, rel, m); err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, c := range children {
+		childRel := path.Join(rel, c.Name)
+		if m.Ignored(childRel, drive.IsFolder(c)) {
+			stats.skipped++
+			continue
+		}
+		if drive.IsFolder(c) {
+			if err := downloadDir(ctx, client, c.Id, filepath.Join(localDir, sanitizeFileName(c.Name)), childRel, m, stats); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := downloadFile(ctx, client, c.Id, localDir); err != nil {
+			log.Printf("Download of %s failed: %v", childRel, err)
+			stats.failed++
+			continue
+		}
+		stats.downloaded++
+	}
+	return nil
+}
+
+// loadIgnoreFile adds the patterns of the ignore file id, stored in the
+// folder rel, to m.
+func loadIgnoreFile(ctx context.Context, client drive.Client, id, rel string, m *ignore.Matcher) error {
+	body, err := client.Download(ctx, id)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %v", path.Join(rel, ignore.FileName), err)
+	}
+	defer body.Close()
+	base := rel
+	if base == "." {
+		base = ""
+	}
+	return m.Read(body, base)
+}
@@ -1,183 +1,215 @@
 package unload
 
 import (
-	"bufio"
+	"context"
+	"errors"
 	"fmt"
 	"io"
 	"log"
-	"net/http"
 	"os"
-	"strconv"
+	"path/filepath"
 	"strings"
 
 	"github.com/spf13/cobra"
 	"github.com/zohaib-a-ahmed/drivebox/pkg/auth"
-	"google.golang.org/api/drive/v3"
+	"github.com/zohaib-a-ahmed/drivebox/pkg/checksum"
+	"github.com/zohaib-a-ahmed/drivebox/pkg/drive"
+	"github.com/zohaib-a-ahmed/drivebox/pkg/export"
+	"github.com/zohaib-a-ahmed/drivebox/pkg/picker"
+	"github.com/zohaib-a-ahmed/drivebox/pkg/plan"
+	"github.com/zohaib-a-ahmed/drivebox/pkg/progress"
+	"github.com/zohaib-a-ahmed/drivebox/pkg/query"
+	"github.com/zohaib-a-ahmed/drivebox/pkg/throttle"
 )
 
+var (
+	// verify controls whether downloads are checked against Drive's checksums.
+	verify bool
+	// exportFormats are the formats Workspace documents are exported to.
+	exportFormats []string
+)
+
+func init() {
+	UnloadCmd.Flags().BoolVar(&verify, "verify", true, "Verify the downloaded content against Drive's checksums")
+	UnloadCmd.Flags().StringSliceVar(&exportFormats, "export", nil, "Export Google Docs, Sheets and Slides to these formats, e.g. docx or pdf,docx (default from config)")
+}
+
 var UnloadCmd = &cobra.Command{
 	Use:   "unload <file_name> <optional_path_destination>",
 	Short: "Download a file from Google Drive",
 	Long: `Download a file from your Google Drive to a local path.
 If no destination is provided, the file will be downloaded to the current directory.
-Enter the number of the file you wish to download, use 'refine <query>' to narrow down your search, or type 'quit' to exit the command.`,
+Matching files are shown in a picker: type to filter, use the arrow keys to move, Tab to select several files and Enter to download.
+With -r, the first argument is the path or id:<ID> of a folder to download with everything inside it.`,
 	Args: cobra.MinimumNArgs(1), // Ensures at least one argument is provided
-	Run: func(cmd *cobra.Command, args []string) {
+	RunE: func(cmd *cobra.Command, args []string) error {
 		fileName := args[0]
 		destination := "./" // Default to current directory if no destination is provided
 		if len(args) > 1 {
 			destination = args[1]
 			// Validate or adjust the destination path if necessary
 			if _, err := os.Stat(destination); os.IsNotExist(err) {
-				log.Fatalf("The specified destination does not exist: %s", destination)
+				return fmt.Errorf("the specified destination does not exist: %s", destination)
 			}
 		}
 
-		driveService, err := auth.CreateDriveService()
+		client, err := auth.CreateDriveClient()
 		if err != nil {
-			log.Fatalf("Failed to create Google Drive service: %v", err)
+			return fmt.Errorf("failed to create Google Drive service: %w", err)
 		}
+		ctx := cmd.Context()
 
-		// Search for the file on Google Drive
-		query := fmt.Sprintf("name contains '%s'", fileName)
+		if recursive {
+			if err := downloadTree(ctx, client, fileName, destination); err != nil {
+				return fmt.Errorf("download failed: %v", err)
+			}
+			return nil
+		}
 
-		call := driveService.Files.List().Q(query).PageSize(6).Fields("files(id, name)")
-		files, err := call.Do()
+		// Search for the file on Google Drive
+		files, err := searchFiles(ctx, client, query.NameContains(fileName))
 		if err != nil {
-			log.Fatalf("Failed to retrieve files: %v", err)
+			return fmt.Errorf("failed to retrieve files: %v", err)
 		}
-		if len(files.Files) == 0 {
+		if len(files) == 0 {
 			fmt.Println("No files found.")
-			return
-		}
-		log.Println("Files found:")
-		for i, file := range files.Files {
-			fmt.Printf("%d: %s \n", i+1, file.Name)
+			return nil
 		}
 
-		handleUserSelection(driveService, files.Files, destination)
+		handleUserSelection(ctx, client, files, destination)
+		return nil
 	},
 }
 
-func getUserInput(prompt string) string {
-	reader := bufio.NewReader(os.Stdin)
-	fmt.Print(prompt)
-	input, _ := reader.ReadString('\n')
-	return strings.TrimSpace(input)
+// maxCandidates caps how many matches are offered by the picker.
+const maxCandidates = 200
+
+func searchFiles(ctx context.Context, client drive.Client, q query.Query) ([]*drive.File, error) {
+	return client.List(ctx, drive.ListOptions{
+		Query:   query.And(q, query.NotFolder(), query.Trashed(false)).String(),
+		Fields:  picker.FileFields,
+		OrderBy: "modifiedTime desc",
+		Limit:   maxCandidates,
+	})
 }
 
-func searchFiles(driveService *drive.Service, query string) ([]*drive.File, error) {
-	call := driveService.Files.List().Q(query).PageSize(6).Fields("files(id, name)")
-	files, err := call.Do()
+func handleUserSelection(ctx context.Context, client drive.Client, files []*drive.File, destination string) {
+	chosen, err := picker.Files(ctx, client, files, picker.Options{Prompt: "Download", Multi: true})
+	if errors.Is(err, picker.ErrCancelled) {
+		fmt.Println("Exiting command.")
+		return
+	}
 	if err != nil {
-		return nil, err
+		log.Printf("Selection failed: %v", err)
+		return
 	}
-	return files.Files, nil
-}
-
-func handleUserSelection(driveService *drive.Service, files []*drive.File, destination string) {
-	for {
-		input := getUserInput("Enter the number of the file to download, 'refine <query>' to search again, or 'quit' to exit: ")
 
-		if input == "quit" {
-			fmt.Println("Exiting command.")
-			return // Exit the function, effectively ending the command
-		}
-
-		if strings.HasPrefix(input, "refine ") {
-			query := strings.TrimSpace(strings.TrimPrefix(input, "refine"))
-			fmt.Println("Refining search with: ", query)
-			var err error
-			files, err = searchFiles(driveService, fmt.Sprintf("name contains '%s'", query))
-			if err != nil {
-				log.Printf("Failed to retrieve files: %v\n", err)
-				continue
-			}
-			if len(files) == 0 {
-				fmt.Println("No files found. Try refining your search.")
-				continue
-			}
-			for i, file := range files {
-				fmt.Printf("%d: %s\n", i+1, file.Name)
-			}
-		} else {
-			selection, err := strconv.Atoi(input)
-			if err != nil || selection < 1 || selection > len(files) {
-				fmt.Println("Invalid selection. Please enter a valid number or 'quit' to exit.")
-				continue
-			}
-			downloadFile(driveService, files[selection-1].Id, destination)
-			break
+	for _, f := range chosen {
+		if err := downloadFile(ctx, client, f.Id, destination); err != nil {
+			log.Printf("Download of %s failed: %v", f.Name, err)
 		}
 	}
 }
 
-func downloadFile(driveService *drive.Service, fileId, destinationPath string) error {
-	file, err := driveService.Files.Get(fileId).Fields("name", "mimeType").Do()
+func downloadFile(ctx context.Context, client drive.Client, fileId, destinationPath string) error {
+	file, err := client.Get(ctx, fileId, "name", "mimeType", "parents", "size", "md5Checksum", "sha256Checksum", "exportLinks")
 	if err != nil {
 		return fmt.Errorf("failed to get file: %v", err)
 	}
 
-	var resp *http.Response
-	var fileName string
+	// Ensure the destination path ends with a separator
+	if !strings.HasSuffix(destinationPath, "/") && !strings.HasSuffix(destinationPath, "\\") {
+		destinationPath += "/"
+	}
 
-	if strings.Contains(file.MimeType, "google-apps") {
-		// Determine the correct export MIME type and corresponding file extension
-		exportMimeType, fileExtension := determineExportFormat(file.MimeType)
-		resp, err = driveService.Files.Export(fileId, exportMimeType).Download()
-		if err != nil {
-			return fmt.Errorf("failed to export and download file: %v", err)
-		}
-		// Append the appropriate file extension to the original file name
-		fileName = sanitizeFileName(file.Name) + fileExtension
-	} else {
+	if !drive.IsWorkspace(file) {
 		// For binary files, directly download and use the original file name
-		resp, err = driveService.Files.Get(fileId).Download()
+		if plan.DryRun {
+			planDownload(ctx, client, file, destinationPath+sanitizeFileName(file.Name), "")
+			return nil
+		}
+		body, err := client.Download(ctx, fileId)
 		if err != nil {
 			return fmt.Errorf("failed to download file: %v", err)
 		}
-		fileName = sanitizeFileName(file.Name)
+		defer body.Close()
+		return saveFile(file, body, destinationPath+sanitizeFileName(file.Name), file.Size, verify)
 	}
-	defer resp.Body.Close()
 
-	// Ensure the destination path ends with a separator
-	if !strings.HasSuffix(destinationPath, "/") && !strings.HasSuffix(destinationPath, "\\") {
-		destinationPath += "/"
+	// Workspace documents are exported to one file per requested format
+	formats, err := export.Choose(file, exportFormats)
+	if err != nil {
+		return err
 	}
+	for _, format := range formats {
+		if plan.DryRun {
+			planDownload(ctx, client, file, destinationPath+sanitizeFileName(file.Name)+format.Extension, "exported as "+format.Name)
+			continue
+		}
+		body, err := client.Export(ctx, fileId, format.MimeType)
+		if err != nil {
+			return fmt.Errorf("failed to export %s as %s: %v", file.Name, format.Name, err)
+		}
+		// Exported documents have no stored checksum or known size
+		err = saveFile(file, body, destinationPath+sanitizeFileName(file.Name)+format.Extension, 0, false)
+		body.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
 
-	// Create a file at the specified destination with the correct file extension
-	outFile, err := os.Create(destinationPath + fileName)
+// planDownload records the download of file to path in the dry-run plan,
+// noting when it would replace an existing local file.
+func planDownload(ctx context.Context, client drive.Client, file *drive.File, path, detail string) {
+	op := plan.Download
+	if _, err := os.Stat(path); err == nil {
+		op = plan.Overwrite
+		detail = strings.TrimPrefix(detail+"; local file exists", "; ")
+	}
+	source, err := drive.NewPathFinder(client).Path(ctx, file)
+	if err != nil {
+		source = file.Name
+	}
+	plan.Add(plan.Action{Op: op, Path: source, Target: path, Detail: detail})
+}
+
+// saveFile writes body to path, showing progress and optionally verifying
+// the content against the checksums of file.
+func saveFile(file *drive.File, body io.Reader, path string, size int64, verify bool) error {
+	outFile, err := os.Create(path)
 	if err != nil {
 		return fmt.Errorf("failed to create file: %v", err)
 	}
 	defer outFile.Close()
 
-	// Write the content to the file
-	_, err = io.Copy(outFile, resp.Body)
+	hasher := checksum.New()
+	bar := progress.New(filepath.Base(path), size)
+	_, err = io.Copy(io.MultiWriter(outFile, hasher), bar.Reader(throttle.Download.Reader(body)))
+	bar.Finish()
 	if err != nil {
 		return fmt.Errorf("failed to write file: %v", err)
 	}
 
-	log.Printf("Download complete: %s\n", destinationPath+fileName)
-	return nil
-}
-
-// determineExportFormat returns the MIME type and file extension for exporting Google Docs formats.
-func determineExportFormat(mimeType string) (exportMimeType, fileExtension string) {
-	switch mimeType {
-	case "application/vnd.google-apps.document":
-		return "application/pdf", ".pdf"
-	case "application/vnd.google-apps.spreadsheet":
-		return "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet", ".xlsx"
-	case "application/vnd.google-apps.presentation":
-		return "application/vnd.openxmlformats-officedocument.presentationml.presentation", ".pptx"
-	// Add more cases as needed
-	default:
-		return "application/pdf", ".pdf" //
+	if verify {
+		if err := hasher.Verify(file.Md5Checksum, file.Sha256Checksum); err != nil {
+			outFile.Close()
+			if rmErr := os.Remove(path); rmErr != nil {
+				log.Printf("Failed to remove corrupt download: %v", rmErr)
+			}
+			return fmt.Errorf("download of %s failed verification: %v", file.Name, err)
+		}
 	}
+
+	log.Printf("Download complete: %s\n", path)
+	return nil
 }
 
 // sanitizeFileName cleans up the file name to prevent path traversal vulnerabilities or issues with illegal characters.
 func sanitizeFileName(name string) string {
+	if name == "." || name == ".." {
+		return "_"
+	}
 	return strings.ReplaceAll(name, "/", "_")
 }
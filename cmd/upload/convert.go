@@ -0,0 +1,58 @@
+package upload
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// Google Workspace types that uploads can be converted to.
+const (
+	docMimeType   = "application/vnd.google-apps.document"
+	sheetMimeType = "application/vnd.google-apps.spreadsheet"
+	slideMimeType = "application/vnd.google-apps.presentation"
+)
+
+// conversion describes how a local file type is imported into Workspace.
+// Drive converts from the type detected for the upload's content.
+type conversion struct {
+	// target is the Workspace type to convert to.
+	target string
+	// ocr marks images and PDFs, which are only converted with --ocr.
+	ocr bool
+}
+
+// conversions maps local file extensions to the Workspace type they import as.
+var conversions = map[string]conversion{
+	".docx": {docMimeType, false},
+	".doc":  {docMimeType, false},
+	".odt":  {docMimeType, false},
+	".rtf":  {docMimeType, false},
+	".txt":  {docMimeType, false},
+	".html": {docMimeType, false},
+	".htm":  {docMimeType, false},
+	".md":   {docMimeType, false},
+	".xlsx": {sheetMimeType, false},
+	".xls":  {sheetMimeType, false},
+	".ods":  {sheetMimeType, false},
+	".csv":  {sheetMimeType, false},
+	".tsv":  {sheetMimeType, false},
+	".pptx": {slideMimeType, false},
+	".ppt":  {slideMimeType, false},
+	".odp":  {slideMimeType, false},
+	".pdf":  {docMimeType, true},
+	".jpg":  {docMimeType, true},
+	".jpeg": {docMimeType, true},
+	".png":  {docMimeType, true},
+	".gif":  {docMimeType, true},
+	".bmp":  {docMimeType, true},
+}
+
+// conversionFor returns how to convert the file at path, and false if it
+// should be uploaded as-is. Images and PDFs are only converted when ocr is set.
+func conversionFor(path string, ocr bool) (conversion, bool) {
+	c, ok := conversions[strings.ToLower(filepath.Ext(path))]
+	if !ok || c.ocr && !ocr {
+		return conversion{}, false
+	}
+	return c, true
+}
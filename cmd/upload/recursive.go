@@ -0,0 +1,144 @@
+package upload
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+
+	"github.com/zohaib-a-ahmed/drivebox/pkg/drive"
+	"github.com/zohaib-a-ahmed/drivebox/pkg/ignore"
+)
+
+var (
+	// recursive allows folders to be uploaded with everything inside them.
+	recursive bool
+	// include and exclude filter the files of recursive uploads.
+	include []string
+	exclude []string
+)
+
+func init() {
+	UploadCmd.PersistentFlags().BoolVarP(&recursive, "recursive", "r", false, "Upload folders and everything inside them")
+	UploadCmd.PersistentFlags().StringSliceVar(&include, "include", nil, "Only upload files matching these glob patterns, e.g. '**/*.go' (recursive uploads)")
+	UploadCmd.PersistentFlags().StringSliceVar(&exclude, "exclude", nil, "Skip files and folders matching these gitignore patterns, e.g. node_modules/ (recursive uploads)")
+}
+
+// UploadPath uploads the file or, with --recursive, the folder at localPath
+// into parentID. --name only renames a single file.
+func UploadPath(ctx context.Context, localPath string, client drive.Client, parentID string) error {
+	info, err := os.Stat(localPath)
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		_, err := UploadFileToDrive(ctx, localPath, uploadName, client, parentID)
+		return err
+	}
+	if uploadName != "" {
+		return fmt.Errorf("%s is a folder; --name can only be used with a single file", localPath)
+	}
+	if !recursive {
+		return fmt.Errorf("%s is a folder; use -r to upload it with everything inside", localPath)
+	}
+	return UploadDirToDrive(ctx, localPath, client, parentID)
+}
+
+// UploadDirToDrive uploads the folder at dir and everything inside it into
+// parentID, reusing folders that already exist in Drive. Files and folders
+// matched by --include, --exclude or a .driveboxignore file are skipped. A
+// failed file is logged and the rest of the tree is still uploaded.
+func UploadDirToDrive(ctx context.Context, dir string, client drive.Client, parentID string) error {
+	matcher, err := ignore.New(include, exclude)
+	if err != nil {
+		return err
+	}
+	if parentID == "" {
+		parentID = drive.Root(client)
+	}
+	name := filepath.Base(filepath.Clean(dir))
+	if name == "." || name == string(filepath.Separator) {
+		abs, err := filepath.Abs(dir)
+		if err != nil {
+			return err
+		}
+		name = filepath.Base(abs)
+	}
+	root, err := drive.EnsureFolder(ctx, client, parentID, name)
+	if err != nil {
+		return fmt.Errorf("failed to create folder %s: %v", name, err)
+	}
+
+	// Each folder keeps its Drive ID and the patterns that apply inside it
+	type folder struct {
+		id      string
+		matcher *ignore.Matcher
+	}
+	folders := map[string]folder{}
+	var uploaded, skipped, failed int
+
+	err = filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+
+		if rel == "." {
+			m := matcher.Clone()
+			if err := m.Load(p, ""); err != nil {
+				return err
+			}
+			folders[rel] = folder{id: root.Id, matcher: m}
+			return nil
+		}
+		parent := folders[path.Dir(rel)]
+		if parent.matcher.Ignored(rel, d.IsDir()) {
+			skipped++
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		if d.IsDir() {
+			f, err := drive.EnsureFolder(ctx, client, parent.id, d.Name())
+			if err != nil {
+				return fmt.Errorf("failed to create folder %s: %v", rel, err)
+			}
+			m := parent.matcher.Clone()
+			if err := m.Load(p, rel); err != nil {
+				return err
+			}
+			folders[rel] = folder{id: f.Id, matcher: m}
+			return nil
+		}
+		if !d.Type().IsRegular() {
+			log.Printf("Skipped %s: not a regular file", rel)
+			skipped++
+			return nil
+		}
+		if _, err := UploadFileToDrive(ctx, p, "", client, parent.id); err != nil {
+			log.Printf("Upload of %s failed: %v", rel, err)
+			failed++
+			return nil
+		}
+		uploaded++
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	log.Printf("Uploaded %d files from %s (%d skipped, %d failed)", uploaded, dir, skipped, failed)
+	if failed > 0 {
+		return fmt.Errorf("%d files failed to upload", failed)
+	}
+	return nil
+}
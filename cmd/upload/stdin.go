@@ -0,0 +1,46 @@
+package upload
+
+import (
+	"bufio"
+	"context"
+	"io"
+
+	"github.com/zohaib-a-ahmed/drivebox/pkg/checksum"
+	"github.com/zohaib-a-ahmed/drivebox/pkg/drive"
+	"github.com/zohaib-a-ahmed/drivebox/pkg/mimetype"
+	"github.com/zohaib-a-ahmed/drivebox/pkg/progress"
+	"github.com/zohaib-a-ahmed/drivebox/pkg/throttle"
+)
+
+// streamChunkSize is the size of each resumable upload request for streams,
+// and so the most of a stream held in memory at once.
+const streamChunkSize = 8 << 20
+
+// sniffLen is how much of a stream is peeked at to detect its MIME type.
+const sniffLen = 512
+
+// UploadStreamToDrive uploads everything read from r as a file called name.
+// The length of a stream is not known in advance, so it is sent in resumable
+// chunks. Unlike files, streams cannot be rewound, so a failed upload is not
+// retried from the start.
+func UploadStreamToDrive(ctx context.Context, r io.Reader, name string, client drive.Client, parentID string) (int, error) {
+	br := bufio.NewReaderSize(r, sniffLen)
+	contentType := mimeType
+	if contentType == "" {
+		// A short stream returns what there is along with an error
+		head, _ := br.Peek(sniffLen)
+		contentType = mimetype.Detect(name, head)
+	}
+	f, opts := newUpload(name, contentType, parentID)
+	opts.ChunkSize = streamChunkSize
+
+	hasher := checksum.New()
+	bar := progress.New(name, 0)
+	res, err := client.Create(ctx, f, bar.Reader(throttle.Upload.Reader(io.TeeReader(br, hasher))), opts)
+	bar.Finish()
+
+	if err != nil {
+		return 400, err
+	}
+	return finishUpload(ctx, client, res, hasher, name, contentType)
+}
@@ -1,57 +1,129 @@
 package upload
 
 import (
+	"context"
 	"fmt"
+	"io"
 	"log"
 	"os"
+	"path/filepath"
+	"strings"
 
 	"github.com/spf13/cobra"
 	"github.com/zohaib-a-ahmed/drivebox/pkg/auth"
-	"google.golang.org/api/drive/v3"
+	"github.com/zohaib-a-ahmed/drivebox/pkg/checksum"
+	"github.com/zohaib-a-ahmed/drivebox/pkg/drive"
+	"github.com/zohaib-a-ahmed/drivebox/pkg/mimetype"
+	"github.com/zohaib-a-ahmed/drivebox/pkg/plan"
+	"github.com/zohaib-a-ahmed/drivebox/pkg/progress"
+	"github.com/zohaib-a-ahmed/drivebox/pkg/query"
+	"github.com/zohaib-a-ahmed/drivebox/pkg/retry"
+	"github.com/zohaib-a-ahmed/drivebox/pkg/throttle"
+)
+
+var (
+	// verify controls whether uploads are checked against Drive's checksums.
+	verify bool
+	// skipExisting skips files that already exist with identical content under the target parent.
+	skipExisting bool
+	// shareWith lists users to share uploads with, as "email" or "email:role".
+	shareWith []string
+	// convert imports documents, spreadsheets and presentations as Google Workspace files.
+	convert bool
+	// ocr converts images and PDFs to Google Docs using optical character recognition.
+	ocr bool
+	// ocrLanguage hints the language of the text in OCR uploads, as an ISO 639-1 code.
+	ocrLanguage string
+	// mimeType overrides the detected MIME type of uploads.
+	mimeType string
+	// uploadName names the uploaded file in Drive; required for standard input.
+	uploadName string
+	// uploadTo is the path of the Drive folder to upload into.
+	uploadTo string
 )
 
 func init() {
 	UploadCmd.AddCommand(UploadParentCmd)
+	UploadCmd.PersistentFlags().BoolVar(&verify, "verify", true, "Verify the uploaded content against Drive's checksums")
+	UploadCmd.PersistentFlags().BoolVar(&skipExisting, "skip-existing", false, "Skip files whose name, size and MD5 match an existing file under the target parent")
+	UploadCmd.PersistentFlags().StringSliceVar(&shareWith, "share-with", nil, "Share the upload with a user, as email or email:role (reader, commenter or writer)")
+	UploadCmd.PersistentFlags().BoolVar(&convert, "convert", false, "Convert documents to Google Docs, spreadsheets to Sheets and presentations to Slides")
+	UploadCmd.PersistentFlags().BoolVar(&ocr, "ocr", false, "Convert images and PDFs to Google Docs using OCR")
+	UploadCmd.PersistentFlags().StringVar(&mimeType, "mime-type", "", "MIME type to store the upload with, instead of detecting it")
+	UploadCmd.PersistentFlags().StringVar(&ocrLanguage, "ocr-language", "", "Language of the text in OCR uploads, as an ISO 639-1 code such as \"en\"")
+	UploadCmd.Flags().StringVar(&uploadName, "name", "", "Name of the uploaded file in Drive, for a single file (required when reading from standard input)")
+	UploadCmd.Flags().StringVar(&uploadTo, "to", "", "Path of the Drive folder to upload into, e.g. /Backups")
+	// Converted files have no checksum to compare against
+	UploadCmd.MarkFlagsMutuallyExclusive("skip-existing", "convert")
+	UploadCmd.MarkFlagsMutuallyExclusive("skip-existing", "ocr")
 }
 
 var UploadCmd = &cobra.Command{
 	Use:   "upload <path_to_file>",
-	Short: "Upload a file to Google Drive",
-	Long:  `Upload a file to your Google Drive. Specify the local path.`,
-	Run: func(cmd *cobra.Command, args []string) {
+	Short: "Upload a file or folder to Google Drive",
+	Long: `Upload a file to your Google Drive. Specify the local path, or - to read from standard input
+with --name, e.g. 'pg_dump mydb | gzip | drivebox upload - --name db.sql.gz --to /Backups'.
+Folders are uploaded with -r, skipping anything matched by --exclude or a .driveboxignore file.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
 
 		// Ensure valid command skeleton
 		if len(args) < 1 {
-			fmt.Println("Path to the local file must be provided. Usage is 'drivebox upload <path_to_file>'")
-			return
+			return fmt.Errorf("path to the local file must be provided. Usage is 'drivebox upload <path_to_file>'")
 		}
 
 		var filePath = args[0]
-		// Ensure unload destination is valid
-		if CheckValidPath(filePath) != 200 {
-			return
+		if filePath == "-" {
+			if uploadName == "" {
+				return fmt.Errorf("a name must be given with --name when uploading from standard input")
+			}
+		} else if err := CheckValidPath(filePath); err != nil {
+			// Ensure upload source is valid
+			return err
 		}
 
-		// Create drive service
-		driveService, err := auth.CreateDriveService()
+		// Create drive client
+		client, err := auth.CreateDriveClient()
 		if err != nil {
-			log.Fatalf("Failed to create Google Drive service: %v", err)
+			return fmt.Errorf("failed to create Google Drive service: %w", err)
+		}
+		ctx := cmd.Context()
+
+		var parentID string
+		if uploadTo != "" {
+			parent, err := drive.Resolve(ctx, client, uploadTo)
+			if err != nil {
+				return fmt.Errorf("upload failed: %v", err)
+			}
+			if !drive.IsFolder(parent) {
+				return fmt.Errorf("upload failed: %s is not a folder", uploadTo)
+			}
+			parentID = parent.Id
 		}
 
-		UploadFileToDrive(filePath, driveService, "")
+		if filePath == "-" {
+			_, err = UploadStreamToDrive(ctx, os.Stdin, uploadName, client, parentID)
+		} else {
+			err = UploadPath(ctx, filePath, client, parentID)
+		}
+		if err != nil {
+			return fmt.Errorf("upload failed: %v", err)
+		}
+		return nil
 	},
 }
 
-func CheckValidPath(path string) (code int) {
+// CheckValidPath returns an error if nothing exists at path.
+func CheckValidPath(path string) error {
 	if _, err := os.Stat(path); os.IsNotExist(err) {
-		fmt.Printf("The file does not exist at the specified path: %s\n", path)
-		return 400
+		return fmt.Errorf("the file does not exist at the specified path: %s", path)
 	}
 	log.Println("Valid file desgination.")
-	return 200
+	return nil
 }
 
-func UploadFileToDrive(filePath string, svc *drive.Service, parentID string) (int, error) {
+// UploadFileToDrive uploads the file at filePath into parentID as name, or
+// under its local name when name is empty.
+func UploadFileToDrive(ctx context.Context, filePath, name string, client drive.Client, parentID string) (int, error) {
 
 	// Gather file information
 	file, err := os.Open(filePath)
@@ -63,7 +135,56 @@ func UploadFileToDrive(filePath string, svc *drive.Service, parentID string) (in
 	if err != nil {
 		return 400, err
 	}
+	if name == "" {
+		name = fileInfo.Name()
+	}
+
+	if skipExisting {
+		existing, err := findIdentical(ctx, client, filePath, name, fileInfo, parentID)
+		if err != nil {
+			return 400, err
+		}
+		if existing != nil {
+			log.Printf("Skipped %s: identical to existing file (ID: %s), saved %s", fileInfo.Name(), existing.Id, progress.FormatBytes(fileInfo.Size()))
+			if err := shareUpload(ctx, client, existing.Id); err != nil {
+				return 500, err
+			}
+			return 200, nil
+		}
+	}
+
+	contentType := mimeType
+	if contentType == "" {
+		if contentType, err = mimetype.DetectFile(filePath); err != nil {
+			return 400, err
+		}
+	}
+	f, opts := newUpload(name, contentType, parentID)
+
+	// Create and upload the file, rewinding it before each attempt and
+	// hashing the bytes as they are sent
+	hasher := checksum.New()
+	bar := progress.New(name, fileInfo.Size())
+	res, err := retry.Call(func() (*drive.File, error) {
+		if _, err := file.Seek(0, io.SeekStart); err != nil {
+			return nil, err
+		}
+		hasher.Reset()
+		opts.Progress = func(now, size int64) { bar.Set(now) }
+		return client.Create(ctx, f, throttle.Upload.Reader(io.TeeReader(file, hasher)), opts)
+	})
+	bar.Finish()
+
+	if err != nil {
+		return 400, err
+	}
+	return finishUpload(ctx, client, res, hasher, filePath, contentType)
+}
 
+// newUpload returns the metadata and options for uploading content of the
+// given type as name under parentID, converting it to a Workspace type if
+// --convert or --ocr ask for it.
+func newUpload(name, contentType, parentID string) (*drive.File, drive.CreateOptions) {
 	// Initialize parents slice based on parentID
 	var parents []string
 	if parentID != "" {
@@ -72,23 +193,119 @@ func UploadFileToDrive(filePath string, svc *drive.Service, parentID string) (in
 
 	// Create File metadata
 	f := &drive.File{
-		Name:    fileInfo.Name(),
-		Parents: parents,
+		Name:     name,
+		Parents:  parents,
+		MimeType: contentType,
 	}
-
-	// Create and upload the file
-	res, err := svc.Files.Create(f).
-		Media(file).
-		ProgressUpdater(func(now, size int64) { fmt.Printf("%d, %d\r", now, size) }).
-		Do()
-
-	if err != nil {
-		return 400, err
+	opts := drive.CreateOptions{
+		Fields:      []string{"id", "mimeType", "md5Checksum", "sha256Checksum"},
+		ContentType: contentType,
+	}
+	if convert || ocr {
+		if c, ok := conversionFor(name, ocr); ok {
+			// Drive converts the content when the requested type differs from the uploaded one
+			f.Name = strings.TrimSuffix(f.Name, filepath.Ext(f.Name))
+			f.MimeType = c.target
+			if c.ocr {
+				opts.OCRLanguage = ocrLanguage
+			}
+		} else if c, ok := conversionFor(name, true); ok && c.ocr {
+			log.Printf("%s is only converted with --ocr; uploading it as-is", name)
+		} else {
+			log.Printf("%s cannot be converted; uploading it as-is", name)
+		}
 	}
+	return f, opts
+}
+
+// finishUpload verifies the content of a completed upload against the bytes
+// that were sent, then reports it and shares it as requested. source names
+// the uploaded content in error messages.
+func finishUpload(ctx context.Context, client drive.Client, res *drive.File, hasher *checksum.Hasher, source, contentType string) (int, error) {
 	if res.Id == "" {
 		return 200, nil
 	}
 
+	switch {
+	case !verify || plan.DryRun:
+		// Nothing was sent in a dry run
+	case drive.IsWorkspace(res):
+		// Drive reports no checksums for converted files
+		log.Println("Checksum verification skipped for a converted file.")
+	default:
+		if err := hasher.Verify(res.Md5Checksum, res.Sha256Checksum); err != nil {
+			// Don't leave a corrupt copy behind in Drive
+			if delErr := client.Delete(ctx, res.Id); delErr != nil {
+				log.Printf("Failed to delete corrupt upload %s: %v", res.Id, delErr)
+			}
+			return 500, fmt.Errorf("upload of %s failed verification: %v", source, err)
+		}
+		log.Println("Checksum verified.")
+	}
+
+	if drive.IsWorkspace(res) {
+		log.Printf("Converted from %s to %s", contentType, res.MimeType)
+	} else {
+		log.Printf("Stored as %s", res.MimeType)
+	}
 	log.Println("Successful Upload!")
+	if err := shareUpload(ctx, client, res.Id); err != nil {
+		return 500, err
+	}
 	return 200, nil
 }
+
+// shareUpload shares the uploaded file id with the users given by --share-with.
+func shareUpload(ctx context.Context, client drive.Client, id string) error {
+	for _, entry := range shareWith {
+		email, role, found := strings.Cut(entry, ":")
+		if !found {
+			role = "reader"
+		}
+		if role != "reader" && role != "commenter" && role != "writer" {
+			return fmt.Errorf("invalid role %q for %s: expected reader, commenter or writer", role, email)
+		}
+		p := &drive.Permission{Type: "user", Role: role, EmailAddress: email}
+		if _, err := client.Share(ctx, id, p, drive.ShareOptions{Notify: true}); err != nil {
+			return fmt.Errorf("uploaded, but failed to share with %s: %v", email, err)
+		}
+		log.Printf("Shared with %s as %s", email, role)
+	}
+	return nil
+}
+
+// findIdentical looks for a file called name under parentID with the same
+// size and MD5 checksum as the local file. It returns nil if there is no such
+// file.
+func findIdentical(ctx context.Context, client drive.Client, filePath, name string, fileInfo os.FileInfo, parentID string) (*drive.File, error) {
+	if parentID == "" {
+		parentID = drive.Root(client)
+	}
+	q := query.And(query.Name(name), query.InParents(parentID), query.Trashed(false))
+	files, err := client.List(ctx, drive.ListOptions{
+		Query:  q.String(),
+		Fields: []string{"id", "name", "size", "md5Checksum"},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("unable to search for existing files: %v", err)
+	}
+
+	var localMD5 string
+	for _, f := range files {
+		if f.Size != fileInfo.Size() || f.Md5Checksum == "" {
+			continue
+		}
+		// Only hash the local file once a candidate of the same size turns up
+		if localMD5 == "" {
+			h, err := checksum.File(filePath)
+			if err != nil {
+				return nil, err
+			}
+			localMD5 = h.MD5()
+		}
+		if f.Md5Checksum == localMD5 {
+			return f, nil
+		}
+	}
+	return nil, nil
+}
@@ -1,62 +1,66 @@
 package upload
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"log"
-	"strconv"
+	"path"
 
 	"github.com/spf13/cobra"
 	"github.com/zohaib-a-ahmed/drivebox/pkg/auth"
-	"google.golang.org/api/drive/v3"
+	"github.com/zohaib-a-ahmed/drivebox/pkg/drive"
+	"github.com/zohaib-a-ahmed/drivebox/pkg/picker"
+	"github.com/zohaib-a-ahmed/drivebox/pkg/query"
 )
 
 var UploadParentCmd = &cobra.Command{
 	Use:   "parent <path_to_file>",
 	Short: "Upload a file to Google Drive under a parent directory",
 	Long:  `Upload a file to your Google Drive. Specify the local path after selecting an existing parent directory`,
-	Run: func(cmd *cobra.Command, args []string) {
+	RunE: func(cmd *cobra.Command, args []string) error {
 
 		// Ensure valid command skeleton
 		if len(args) < 1 {
-			fmt.Println("Path to the local file must be provided. Usage is 'drivebox upload <path_to_file>'")
-			return
+			return fmt.Errorf("path to the local file must be provided. Usage is 'drivebox upload parent <path_to_file>'")
 		}
 
 		var filePath = args[0]
-		// Ensure unload destination is valid
-		if CheckValidPath(filePath) != 200 {
-			return
+		// Ensure upload source is valid
+		if err := CheckValidPath(filePath); err != nil {
+			return err
 		}
 
-		// Create drive service
-		driveService, err := auth.CreateDriveService()
+		// Create drive client
+		client, err := auth.CreateDriveClient()
 		if err != nil {
-			log.Fatalf("Failed to create Google Drive service: %v", err)
+			return fmt.Errorf("failed to create Google Drive service: %w", err)
 		}
+		ctx := cmd.Context()
 
-		choice := PromptUserForAction()
-		switch choice {
+		var parentID string
+		switch PromptUserForAction() {
 		case "1":
-			parentID, err := SearchParentDirectory(driveService)
-			if err != nil {
-				log.Println("Error:", err)
-				return
+			if parentID, err = SearchParentDirectory(ctx, client); err != nil {
+				return err
+			}
+			if parentID == "" {
+				return nil
 			}
-			fmt.Println(parentID)
-			UploadFileToDrive(filePath, driveService, parentID)
 		case "2":
-			parentID, err := CreateParentDirectory(driveService)
-			if err != nil {
-				log.Println("Error:", err)
-				return
+			if parentID, err = CreateParentDirectory(ctx, client); err != nil {
+				return err
 			}
-			UploadFileToDrive(filePath, driveService, parentID)
 		case "3":
 			fmt.Println("Exiting... Use command 'drivebox upload <path_to_file>' to upload under no directory.")
-			return
+			return nil
 		default:
-			fmt.Println("Invalid choice. Exiting...")
+			return fmt.Errorf("invalid choice")
+		}
+		if err := UploadPath(ctx, filePath, client, parentID); err != nil {
+			return fmt.Errorf("upload failed: %v", err)
 		}
+		return nil
 	},
 }
 
@@ -65,102 +69,61 @@ func PromptUserForAction() string {
 	fmt.Println("1: Search for a parent directory")
 	fmt.Println("2: Create a new parent directory")
 	fmt.Println("3: Quit")
-	fmt.Print("Selection: ")
-	var choice string
-	fmt.Scanln(&choice)
-	return choice
+	return picker.Prompt("Selection: ")
 }
 
-func getUserInput(prompt string) string {
-	fmt.Print(prompt)
-	var input string
-	fmt.Scanln(&input)
-	return input
-}
+// maxCandidates caps how many folders are offered by the picker.
+const maxCandidates = 1000
 
-func searchFiles(svc *drive.Service, query string) ([]*drive.File, error) {
-	searchQuery := fmt.Sprintf("name contains '%s' and mimeType = 'application/vnd.google-apps.folder'", query)
-	call := svc.Files.List().Q(searchQuery).PageSize(5).Fields("files(id, name)")
-	files, err := call.Do()
+func SearchParentDirectory(ctx context.Context, client drive.Client) (string, error) {
+	// Narrow the search on Drive first so older folders can still be found
+	var nameQuery query.Query
+	if name := picker.Prompt("Folder name to search for (leave empty for recent folders): "); name != "" {
+		nameQuery = query.NameContains(name)
+	}
+	folders, err := client.List(ctx, drive.ListOptions{
+		Query:   query.And(query.Folder(), query.Trashed(false), nameQuery).String(),
+		Fields:  picker.FileFields,
+		OrderBy: "modifiedTime desc",
+		Limit:   maxCandidates,
+	})
 	if err != nil {
-		return nil, err
+		return "", fmt.Errorf("failed to retrieve folders: %v", err)
 	}
-	return files.Files, nil
-}
-
-func SearchParentDirectory(svc *drive.Service) (string, error) {
-	var files []*drive.File
-	var err error
-
-	for {
-		input := getUserInput("Enter Drive parent directory name or 'quit' to exit: ")
-
-		if input == "quit" {
-			fmt.Println("Exiting...")
-			return "", nil
-		}
-
-		if len(files) == 0 { // Initial search or no previous search results
-			query := input
-			files, err = searchFiles(svc, query)
-			if err != nil {
-				log.Printf("Failed to retrieve files: %v\n", err)
-				continue
-			}
-			if len(files) == 0 {
-				fmt.Println("No files found. Try refining your search query.")
-				continue
-			}
-		}
-
-		for i, file := range files {
-			fmt.Printf("%d: %s (ID: %s)\n", i+1, file.Name, file.Id)
-		}
-
-		// Selection loop
-		for {
-			input = getUserInput("Selection: ")
-
-			if input == "quit" {
-				fmt.Println("Exiting...")
-				return "", nil
-			} else {
-				selection, err := strconv.Atoi(input)
-				if err != nil || selection < 1 || selection > len(files) {
-					fmt.Println("Invalid selection - try again...")
-					continue
-				}
-				return files[selection-1].Id, nil
-			}
-		}
+	if len(folders) == 0 {
+		fmt.Println("No matching folders found. Create a new parent directory instead.")
+		return "", nil
 	}
-}
 
-func CreateParentDirectory(svc *drive.Service) (string, error) {
-
-	dirName := getUserInput("Name the new directory: ")
-
-	// Search for an existing directory with the same name
-	query := fmt.Sprintf("mimeType='application/vnd.google-apps.folder' and name='%s' and trashed=false", dirName)
-	call := svc.Files.List().Q(query).Fields("files(id, name)")
-	files, err := call.Do()
+	chosen, err := picker.Files(ctx, client, folders, picker.Options{Prompt: "Parent directory"})
+	if errors.Is(err, picker.ErrCancelled) {
+		fmt.Println("Exiting...")
+		return "", nil
+	}
 	if err != nil {
-		return "", fmt.Errorf("unable to search for directories: %v", err)
+		return "", err
 	}
+	return chosen[0].Id, nil
+}
+
+func CreateParentDirectory(ctx context.Context, client drive.Client) (string, error) {
 
-	if len(files.Files) > 0 {
-		return "", fmt.Errorf("a directory with the name '%s' already exists", dirName)
+	dirPath := picker.Prompt("Path of the new directory (e.g. Backups/2024): ")
+	if dirPath == "" {
+		return "", fmt.Errorf("a directory name is required")
 	}
 
-	dirMetadata := &drive.File{
-		Name:     dirName,
-		MimeType: "application/vnd.google-apps.folder",
+	// Create any missing parents, but the directory itself must be new
+	if parent := path.Dir(path.Clean("/" + dirPath)); parent != "/" {
+		if _, err := drive.MkdirAll(ctx, client, parent); err != nil {
+			return "", fmt.Errorf("failed to create directory: %v", err)
+		}
 	}
-	newDir, err := svc.Files.Create(dirMetadata).Fields("id").Do()
+	newDir, err := drive.Mkdir(ctx, client, dirPath)
 	if err != nil {
 		return "", fmt.Errorf("failed to create directory: %v", err)
 	}
 
-	log.Printf("New directory, %s, created successfully.", dirName)
+	log.Printf("New directory, %s, created successfully.", dirPath)
 	return newDir.Id, nil
 }
@@ -0,0 +1,161 @@
+package watch
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/zohaib-a-ahmed/drivebox/pkg/drive"
+	"github.com/zohaib-a-ahmed/drivebox/pkg/format"
+)
+
+// Event types, in the order they take precedence when a change is several
+// things at once.
+const (
+	Created    = "created"
+	Deleted    = "deleted"
+	Trashed    = "trashed"
+	Restored   = "restored"
+	Moved      = "moved"
+	Renamed    = "renamed"
+	Permission = "permission-changed"
+	Modified   = "modified"
+)
+
+// Event is a classified change to an item.
+type Event struct {
+	Type     string `json:"type"`
+	Time     string `json:"time"`
+	ID       string `json:"id"`
+	Name     string `json:"name,omitempty"`
+	Path     string `json:"path,omitempty"`
+	OldPath  string `json:"oldPath,omitempty"`
+	MimeType string `json:"mimeType,omitempty"`
+	User     string `json:"user,omitempty"`
+}
+
+// snapshot is what is remembered about an item between polls, to tell what
+// kind of change the next one is.
+type snapshot struct {
+	Name        string `json:"name"`
+	Path        string `json:"path"`
+	Parents     string `json:"parents"`
+	Folder      bool   `json:"folder,omitempty"`
+	Md5         string `json:"md5,omitempty"`
+	Modified    string `json:"modified,omitempty"`
+	Trashed     bool   `json:"trashed,omitempty"`
+	Permissions string `json:"permissions,omitempty"`
+}
+
+func snapshotOf(f *drive.File, path string) *snapshot {
+	return &snapshot{
+		Name:        f.Name,
+		Path:        path,
+		Parents:     strings.Join(f.Parents, ","),
+		Folder:      drive.IsFolder(f),
+		Md5:         f.Md5Checksum,
+		Modified:    f.ModifiedTime,
+		Trashed:     f.Trashed,
+		Permissions: permissionKey(f),
+	}
+}
+
+// permissionKey summarizes who has access to f. Roles are included when the
+// permissions themselves are known, so a changed role is noticed too. Drive
+// leaves them out for items in shared drives, where only added and removed
+// permissions are noticed.
+func permissionKey(f *drive.File) string {
+	var keys []string
+	if len(f.Permissions) > 0 {
+		for _, p := range f.Permissions {
+			keys = append(keys, p.Id+":"+p.Role)
+		}
+	} else {
+		keys = append(keys, f.PermissionIds...)
+	}
+	sort.Strings(keys)
+	return strings.Join(keys, ",")
+}
+
+// classify returns the type of change from prev to f, or "" if nothing
+// watched about the item changed. since is when the previous poll happened;
+// items not seen before are new if they were created after it.
+func classify(prev *snapshot, f *drive.File, path string, since time.Time) string {
+	cur := snapshotOf(f, path)
+	if prev == nil {
+		if f.Trashed {
+			return Trashed
+		}
+		if created, err := time.Parse(time.RFC3339, f.CreatedTime); err == nil && !created.Before(since) {
+			return Created
+		}
+		if in != "" {
+			// Everything already in the folder was seen when watching started
+			return Moved
+		}
+		return Modified
+	}
+	switch {
+	case cur.Trashed && !prev.Trashed:
+		return Trashed
+	case !cur.Trashed && prev.Trashed:
+		return Restored
+	case cur.Parents != prev.Parents:
+		return Moved
+	case cur.Name != prev.Name:
+		return Renamed
+	case cur.Permissions != prev.Permissions:
+		return Permission
+	case cur.Md5 != prev.Md5 || cur.Modified != prev.Modified:
+		return Modified
+	}
+	return ""
+}
+
+// emit writes e to standard output as text or a line of JSON.
+func emit(e *Event) {
+	if asJSON {
+		data, _ := json.Marshal(e)
+		fmt.Println(string(data))
+		return
+	}
+	line := fmt.Sprintf("%s  %-18s  %s", format.Time(e.Time, format.Second), e.Type, e.Path)
+	if e.OldPath != "" && e.OldPath != e.Path {
+		line += " (from " + e.OldPath + ")"
+	}
+	if e.User != "" {
+		line += " by " + e.User
+	}
+	fmt.Println(line)
+}
+
+// runHook runs the --exec command for e through the shell. The event is
+// passed as DRIVEBOX_EVENT_* environment variables and as JSON on stdin.
+func runHook(command string, e *Event) error {
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.Command("cmd", "/C", command)
+	} else {
+		cmd = exec.Command("sh", "-c", command)
+	}
+	data, _ := json.Marshal(e)
+	cmd.Stdin = strings.NewReader(string(data) + "\n")
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	cmd.Env = append(os.Environ(),
+		"DRIVEBOX_EVENT_TYPE="+e.Type,
+		"DRIVEBOX_EVENT_ID="+e.ID,
+		"DRIVEBOX_EVENT_NAME="+e.Name,
+		"DRIVEBOX_EVENT_PATH="+e.Path,
+		"DRIVEBOX_EVENT_OLD_PATH="+e.OldPath,
+		"DRIVEBOX_EVENT_MIME_TYPE="+e.MimeType,
+		"DRIVEBOX_EVENT_USER="+e.User,
+		"DRIVEBOX_EVENT_TIME="+e.Time,
+	)
+	return cmd.Run()
+}
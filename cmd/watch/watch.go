@@ -0,0 +1,407 @@
+package watch
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/zohaib-a-ahmed/drivebox/pkg/auth"
+	"github.com/zohaib-a-ahmed/drivebox/pkg/cache"
+	"github.com/zohaib-a-ahmed/drivebox/pkg/drive"
+	"github.com/zohaib-a-ahmed/drivebox/pkg/plan"
+	"github.com/zohaib-a-ahmed/drivebox/pkg/query"
+)
+
+var (
+	in        string
+	queryText string
+	interval  time.Duration
+	asJSON    bool
+	hook      string
+	once      bool
+	reset     bool
+)
+
+func init() {
+	WatchCmd.Flags().StringVar(&in, "in", "", "Only report changes inside this folder and its subfolders (path or id:<ID>)")
+	WatchCmd.Flags().StringVar(&queryText, "query", "", "Only report changes to items matching this Drive query, e.g. \"name contains 'report'\"")
+	WatchCmd.Flags().DurationVar(&interval, "interval", 30*time.Second, "How often to ask Drive for changes")
+	WatchCmd.Flags().BoolVar(&asJSON, "json", false, "Print each event as a line of JSON")
+	WatchCmd.Flags().StringVar(&hook, "exec", "", "Run this shell command for every event; details are in DRIVEBOX_EVENT_* variables and as JSON on stdin")
+	WatchCmd.Flags().BoolVar(&once, "once", false, "Report the changes since the last run and exit instead of polling")
+	WatchCmd.Flags().BoolVar(&reset, "reset", false, "Forget the saved position and only report changes from now on")
+}
+
+var WatchCmd = &cobra.Command{
+	Use:   "watch",
+	Short: "Report changes to files as they happen",
+	Long: `Poll the Drive Changes API and report items that are created, modified, renamed, moved,
+trashed, restored, deleted or have their sharing changed. Limit the report to a folder tree with
+--in or to items matching a Drive query with --query.
+
+The position in the change stream is saved after every poll, so a later run (or a --once run from
+cron) picks up where the previous one stopped without missing or repeating changes. Each --in and
+--query combination has its own position. With --exec, the command runs once per event.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if interval < time.Second {
+			return fmt.Errorf("--interval must be at least 1s")
+		}
+		if asJSON {
+			// Keep the plan out of the event stream
+			plan.UseStderr()
+		}
+		var expr query.Expr
+		if queryText != "" {
+			var err error
+			if expr, err = query.Parse(queryText); err != nil {
+				return fmt.Errorf("invalid --query: %v", err)
+			}
+		}
+
+		// Paths must reflect Drive as it is now, not as of the last cache refresh
+		cache.Disabled = true
+		client, err := auth.CreateDriveClient()
+		if err != nil {
+			return fmt.Errorf("failed to create Google Drive service: %w", err)
+		}
+		ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+
+		if expr != nil && strings.Contains(queryText, "'root'") {
+			root, err := client.Get(ctx, drive.Root(client), "id")
+			if err != nil {
+				return fmt.Errorf("failed to get the root folder: %v", err)
+			}
+			if expr, err = query.Parse(strings.ReplaceAll(queryText, "'root'", "'"+root.Id+"'")); err != nil {
+				return fmt.Errorf("invalid --query: %v", err)
+			}
+		}
+
+		if plan.DryRun {
+			log.Println("Dry run: --exec commands are not run and the position is not saved.")
+		}
+		w := &watcher{client: client, expr: expr}
+		if err := w.start(ctx); err != nil {
+			return err
+		}
+		if once && !w.fresh {
+			return w.poll(ctx)
+		}
+		if once {
+			log.Println("Saved the current position; the next run reports changes made from now on.")
+			return nil
+		}
+
+		log.Printf("Watching for changes every %s; press Ctrl+C to stop.", interval)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-ticker.C:
+			}
+			if err := w.poll(ctx); err != nil {
+				if ctx.Err() != nil {
+					return nil
+				}
+				// Keep watching through outages; the position is only saved after a good poll
+				log.Printf("Failed to check for changes: %v", err)
+			}
+		}
+	},
+}
+
+// state is what a watch remembers between polls and runs.
+type state struct {
+	// Token is the Changes page token the next poll starts from.
+	Token string `json:"pageToken"`
+	// Since is when the last poll started; items created after it are new.
+	Since time.Time `json:"since"`
+	// FolderID is the folder given with --in.
+	FolderID string `json:"folderId,omitempty"`
+	// Items holds the last known state of the items being watched by ID.
+	Items map[string]*snapshot `json:"items"`
+
+	path string
+}
+
+type watcher struct {
+	client drive.Client
+	expr   query.Expr
+	state  *state
+	// fresh is set when there was no saved position to continue from.
+	fresh bool
+}
+
+// start loads the saved position for this watch, or saves a new one.
+func (w *watcher) start(ctx context.Context) error {
+	path, err := statePath(w.client)
+	if err != nil {
+		return err
+	}
+	if !reset {
+		data, err := os.ReadFile(path)
+		if err == nil {
+			w.state = &state{path: path}
+			if err := json.Unmarshal(data, w.state); err != nil {
+				return fmt.Errorf("saved watch position %s is corrupt; run with --reset: %v", path, err)
+			}
+			if w.state.Items == nil {
+				w.state.Items = make(map[string]*snapshot)
+			}
+			return nil
+		}
+		if !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	}
+
+	// Take the token first so nothing changed while seeding is missed
+	token, err := w.client.StartPageToken(ctx, "")
+	if err != nil {
+		return fmt.Errorf("failed to get a changes token: %v", err)
+	}
+	w.fresh = true
+	w.state = &state{Token: token, Since: time.Now(), Items: make(map[string]*snapshot), path: path}
+	if in != "" {
+		if err := w.seed(ctx); err != nil {
+			return err
+		}
+	}
+	return w.save()
+}
+
+// seed records every item under the --in folder, so that later changes to
+// them can be told apart from items moved in from elsewhere.
+func (w *watcher) seed(ctx context.Context) error {
+	folder, err := drive.Lookup(ctx, w.client, in, drive.ChangeFields...)
+	if err != nil {
+		return fmt.Errorf("failed to find %s: %v", in, err)
+	}
+	if !drive.IsFolder(folder) {
+		return fmt.Errorf("%s is not a folder", in)
+	}
+	folderPath, err := drive.NewPathFinder(w.client).Path(ctx, folder)
+	if err != nil {
+		return fmt.Errorf("failed to get the path of %s: %v", in, err)
+	}
+	w.state.FolderID = folder.Id
+	w.state.Items[folder.Id] = snapshotOf(folder, folderPath)
+
+	log.Printf("Recording the current contents of %s...", folderPath)
+	pending := []*drive.File{folder}
+	for len(pending) > 0 {
+		parent := pending[0]
+		pending = pending[1:]
+		children, err := drive.Children(ctx, w.client, parent.Id, drive.ChangeFields...)
+		if err != nil {
+			return fmt.Errorf("failed to list %s: %v", w.state.Items[parent.Id].Path, err)
+		}
+		for _, f := range children {
+			w.state.Items[f.Id] = snapshotOf(f, childPath(w.state.Items[parent.Id].Path, f.Name))
+			if drive.IsFolder(f) {
+				pending = append(pending, f)
+			}
+		}
+	}
+	return nil
+}
+
+// poll reports the changes since the saved position and saves the new one.
+func (w *watcher) poll(ctx context.Context) error {
+	started := time.Now()
+	changes, token, err := w.client.Changes(ctx, w.state.Token, "")
+	if err != nil {
+		return err
+	}
+
+	paths := drive.NewPathFinder(w.client)
+	for _, ch := range latest(changes) {
+		e := w.event(ctx, ch, paths)
+		if e == nil {
+			continue
+		}
+		emit(e)
+		// A dry run only reports what happened
+		if hook != "" && !plan.DryRun {
+			if err := runHook(hook, e); err != nil {
+				log.Printf("Command for %s of %s failed: %v", e.Type, e.Path, err)
+			}
+		}
+	}
+
+	if token != "" {
+		w.state.Token = token
+	}
+	w.state.Since = started
+	return w.save()
+}
+
+// latest keeps the last change to each file, in the order of those changes.
+// Drive may list a file once per change, but always with its current state,
+// so the earlier entries add nothing.
+func latest(changes []*drive.Change) []*drive.Change {
+	last := make(map[string]int)
+	for i, ch := range changes {
+		if ch.ChangeType == "" || ch.ChangeType == "file" {
+			last[ch.FileId] = i
+		}
+	}
+	var out []*drive.Change
+	for i, ch := range changes {
+		if j, ok := last[ch.FileId]; ok && i == j {
+			out = append(out, ch)
+		}
+	}
+	return out
+}
+
+// event classifies a change and updates what is known about the item. It
+// returns nil for changes outside the watch or that do not matter.
+func (w *watcher) event(ctx context.Context, ch *drive.Change, paths *drive.PathFinder) *Event {
+	prev := w.state.Items[ch.FileId]
+	if ch.Removed || ch.File == nil {
+		if prev == nil && (w.state.FolderID != "" || w.expr != nil) {
+			return nil
+		}
+		e := &Event{Type: Deleted, Time: ch.Time, ID: ch.FileId, Path: ch.FileId}
+		if prev != nil {
+			e.Name, e.Path = prev.Name, prev.Path
+			w.forget(ch.FileId, prev)
+		}
+		return e
+	}
+
+	f := ch.File
+	inside := w.inside(f)
+	matches := w.expr == nil || w.expr.Match(f)
+	if prev == nil && !(inside && matches) {
+		return nil
+	}
+	p := w.path(ctx, paths, f)
+	typ := classify(prev, f, p, w.state.Since)
+	if inside && matches {
+		w.remember(f, p, prev)
+	} else {
+		// The item left the folder or stopped matching; report how and stop following it
+		w.forget(f.Id, prev)
+		if !inside {
+			typ = Moved
+		}
+	}
+	if typ == "" {
+		return nil
+	}
+
+	e := &Event{Type: typ, Time: ch.Time, ID: f.Id, Name: f.Name, Path: p, MimeType: f.MimeType}
+	if prev != nil && prev.Path != p {
+		e.OldPath = prev.Path
+	}
+	if u := f.LastModifyingUser; u != nil {
+		e.User = u.DisplayName
+		if e.User == "" {
+			e.User = u.EmailAddress
+		}
+	}
+	return e
+}
+
+// inside reports whether f is in the --in folder tree. A watch without --in
+// covers the whole drive.
+func (w *watcher) inside(f *drive.File) bool {
+	if w.state.FolderID == "" || f.Id == w.state.FolderID {
+		return true
+	}
+	for _, parent := range f.Parents {
+		if s, ok := w.state.Items[parent]; ok && s.Folder {
+			return true
+		}
+	}
+	return false
+}
+
+// path returns the Drive path of f. Inside the --in folder it is built from
+// the recorded folders; elsewhere it is looked up, falling back to the name.
+func (w *watcher) path(ctx context.Context, paths *drive.PathFinder, f *drive.File) string {
+	if s, ok := w.state.Items[f.Id]; ok && f.Id == w.state.FolderID {
+		return s.Path
+	}
+	for _, parent := range f.Parents {
+		if s, ok := w.state.Items[parent]; ok && s.Folder {
+			return childPath(s.Path, f.Name)
+		}
+	}
+	p, err := paths.Path(ctx, f)
+	if err != nil || p == "" {
+		return f.Name
+	}
+	return p
+}
+
+// remember records the new state of f. When a folder is renamed or moved, the
+// recorded paths of everything inside it move with it.
+func (w *watcher) remember(f *drive.File, p string, prev *snapshot) {
+	if prev != nil && prev.Folder && prev.Path != p {
+		for _, s := range w.state.Items {
+			if rest, ok := strings.CutPrefix(s.Path, prev.Path+"/"); ok {
+				s.Path = p + "/" + rest
+			}
+		}
+	}
+	w.state.Items[f.Id] = snapshotOf(f, p)
+}
+
+// forget stops following an item and, for a folder, everything inside it.
+func (w *watcher) forget(id string, prev *snapshot) {
+	delete(w.state.Items, id)
+	if prev == nil || !prev.Folder {
+		return
+	}
+	for childID, s := range w.state.Items {
+		if strings.HasPrefix(s.Path, prev.Path+"/") {
+			delete(w.state.Items, childID)
+		}
+	}
+}
+
+func childPath(folder, name string) string {
+	return strings.TrimSuffix(folder, "/") + "/" + name
+}
+
+// save writes the position of the watch, except in a dry run, which leaves
+// it where it was so the changes are reported again by the next real run.
+func (w *watcher) save() error {
+	if plan.DryRun {
+		return nil
+	}
+	data, err := json.Marshal(w.state)
+	if err != nil {
+		return err
+	}
+	return cache.WriteFile(w.state.path, data)
+}
+
+// statePath returns where the position of this watch is saved: next to the
+// metadata cache, one file per account, drive and combination of --in and
+// --query.
+func statePath(c drive.Client) (string, error) {
+	dir, err := cache.Dir()
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256([]byte(in + "\x00" + queryText))
+	return filepath.Join(dir, "watch-"+cache.Scope(c)+"-"+hex.EncodeToString(sum[:6])+".json"), nil
+}
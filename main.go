@@ -2,27 +2,89 @@ package main
 
 import (
 	"fmt"
+	"log"
 	"os"
 
 	"github.com/spf13/cobra"
+	cachecmd "github.com/zohaib-a-ahmed/drivebox/cmd/cache"
+	"github.com/zohaib-a-ahmed/drivebox/cmd/cat"
+	"github.com/zohaib-a-ahmed/drivebox/cmd/cp"
+	"github.com/zohaib-a-ahmed/drivebox/cmd/drives"
+	"github.com/zohaib-a-ahmed/drivebox/cmd/info"
+	"github.com/zohaib-a-ahmed/drivebox/cmd/mkdir"
+	"github.com/zohaib-a-ahmed/drivebox/cmd/mv"
+	"github.com/zohaib-a-ahmed/drivebox/cmd/rename"
+	"github.com/zohaib-a-ahmed/drivebox/cmd/rm"
+	"github.com/zohaib-a-ahmed/drivebox/cmd/search"
+	"github.com/zohaib-a-ahmed/drivebox/cmd/share"
+	"github.com/zohaib-a-ahmed/drivebox/cmd/trash"
 	"github.com/zohaib-a-ahmed/drivebox/cmd/unload"
 	"github.com/zohaib-a-ahmed/drivebox/cmd/upload"
+	"github.com/zohaib-a-ahmed/drivebox/cmd/watch"
 	"github.com/zohaib-a-ahmed/drivebox/pkg/auth"
+	"github.com/zohaib-a-ahmed/drivebox/pkg/cache"
+	"github.com/zohaib-a-ahmed/drivebox/pkg/plan"
+	"github.com/zohaib-a-ahmed/drivebox/pkg/progress"
+	"github.com/zohaib-a-ahmed/drivebox/pkg/throttle"
 )
 
 var rootCmd = &cobra.Command{
 	Use:   "drivebox",
 	Short: "Drivebox is a CLI tool for managing Google Drive files",
 	Long:  `Drivebox allows you to easily upload, download, and manage your Google Drive files from the command line.`,
+	// Errors are printed once by main; usage is only useful for argument errors
+	SilenceErrors: true,
+	SilenceUsage:  true,
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		if !plan.DryRun {
+			return nil
+		}
+		if err := plan.CheckFormat(); err != nil {
+			return err
+		}
+		// Messages describe what would happen, not what did
+		log.SetPrefix("dry-run: ")
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.PersistentFlags().BoolVarP(&progress.Quiet, "quiet", "q", false, "Suppress transfer progress output")
+	rootCmd.PersistentFlags().Var(throttle.Upload, "limit-upload", "Limit total upload bandwidth, e.g. 5MB/s")
+	rootCmd.PersistentFlags().Var(throttle.Download, "limit-download", "Limit total download bandwidth, e.g. 5MB/s")
+	rootCmd.PersistentFlags().StringVar(&auth.SharedDrive, "drive", "", "Work in a shared drive, given by name or ID, instead of My Drive")
+	rootCmd.PersistentFlags().BoolVar(&cache.Disabled, "no-cache", false, "Query Drive directly instead of the local metadata cache")
+	rootCmd.PersistentFlags().BoolVar(&plan.DryRun, "dry-run", false, "Look everything up but only print the changes that would be made")
+	rootCmd.PersistentFlags().StringVar(&plan.Format, "plan-format", "text", "Format of the --dry-run plan: text or json")
 }
 
 func main() {
 	rootCmd.AddCommand(auth.AuthCmd)
 	rootCmd.AddCommand(upload.UploadCmd)
 	rootCmd.AddCommand(unload.UnloadCmd)
+	rootCmd.AddCommand(search.SearchCmd)
+	rootCmd.AddCommand(mkdir.MkdirCmd)
+	rootCmd.AddCommand(mv.MvCmd)
+	rootCmd.AddCommand(cp.CpCmd)
+	rootCmd.AddCommand(rm.RmCmd)
+	rootCmd.AddCommand(trash.TrashCmd)
+	rootCmd.AddCommand(info.InfoCmd)
+	rootCmd.AddCommand(share.ShareCmd)
+	rootCmd.AddCommand(drives.DrivesCmd)
+	rootCmd.AddCommand(rename.RenameCmd)
+	rootCmd.AddCommand(cat.CatCmd)
+	rootCmd.AddCommand(cachecmd.CacheCmd)
+	rootCmd.AddCommand(watch.WatchCmd)
 
-	if err := rootCmd.Execute(); err != nil {
-		fmt.Println(err)
+	err := rootCmd.Execute()
+	if plan.DryRun && plan.CheckFormat() == nil {
+		if perr := plan.Print(); perr != nil {
+			fmt.Fprintln(os.Stderr, perr)
+		}
+	}
+	if err != nil {
+		// Keep errors out of output that may be piped, e.g. by 'drivebox cat'
+		fmt.Fprintln(os.Stderr, err)
 		os.Exit(1)
 	}
 }
@@ -11,6 +11,9 @@ import (
 
 	"github.com/pkg/browser"
 	"github.com/spf13/cobra"
+	"github.com/zohaib-a-ahmed/drivebox/pkg/cache"
+	driveclient "github.com/zohaib-a-ahmed/drivebox/pkg/drive"
+	"github.com/zohaib-a-ahmed/drivebox/pkg/plan"
 	"golang.org/x/oauth2"
 	"google.golang.org/api/drive/v3"
 	"google.golang.org/api/option"
@@ -103,8 +106,19 @@ func loadTokenFromFile(file string) (*oauth2.Token, error) {
 }
 
 func CreateDriveService() (*drive.Service, error) {
-	// Create a new OAuth2 HTTP client using the token
 	ctx := context.Background()
+
+	// Talk to an alternate endpoint, such as a fakedrive server, without OAuth
+	if endpoint := os.Getenv("DRIVEBOX_API_ENDPOINT"); endpoint != "" {
+		cache.Account = endpoint
+		srv, err := drive.NewService(ctx, option.WithEndpoint(endpoint), option.WithoutAuthentication())
+		if err != nil {
+			return nil, fmt.Errorf("cannot create drive service: %v", err)
+		}
+		return srv, nil
+	}
+
+	// Create a new OAuth2 HTTP client using the token
 	config := NewConfig()
 
 	token, err := loadTokenFromFile("token.json")
@@ -112,6 +126,8 @@ func CreateDriveService() (*drive.Service, error) {
 		log.Fatalf("Unable to load token; Check authenticatio with 'drivebox auth check' \n %v", err)
 	}
 
+	// The refresh token is issued per sign-in, so a new sign-in gets its own cache
+	cache.Account = token.RefreshToken
 	client := config.Client(ctx, token)
 
 	// Create a new Google Drive service client
@@ -121,3 +137,35 @@ func CreateDriveService() (*drive.Service, error) {
 	}
 	return srv, nil
 }
+
+// SharedDrive is the name or ID of the shared drive commands work in, set by
+// the global --drive flag. Empty means My Drive.
+var SharedDrive string
+
+// CreateDriveClient returns a drive.Client backed by the authenticated Drive
+// service, confined to SharedDrive if one is selected. Lookups are answered
+// from the metadata cache once one has been built. In a dry run, changes made
+// through it are only planned.
+func CreateDriveClient() (driveclient.Client, error) {
+	srv, err := CreateDriveService()
+	if err != nil {
+		return nil, err
+	}
+	var client driveclient.Client = driveclient.NewGoogle(srv)
+	if SharedDrive != "" {
+		d, err := driveclient.FindDrive(context.Background(), client, SharedDrive)
+		if err != nil {
+			return nil, err
+		}
+		client = driveclient.InSharedDrive(client, d.Id)
+	}
+	cached, err := cache.Open(context.Background(), client)
+	if err != nil {
+		log.Printf("Not using the metadata cache: %v", err)
+	}
+	client = cached
+	if plan.DryRun {
+		client = plan.Wrap(client)
+	}
+	return client, nil
+}
@@ -0,0 +1,44 @@
+package auth
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/zohaib-a-ahmed/drivebox/pkg/drive"
+	"github.com/zohaib-a-ahmed/drivebox/pkg/fakedrive"
+)
+
+func TestMain(m *testing.M) {
+	code := m.Run()
+	// init creates an empty .env in the package folder when there is none
+	if info, err := os.Stat(".env"); err == nil && info.Size() == 0 {
+		os.Remove(".env")
+	}
+	os.Exit(code)
+}
+
+func TestCreateDriveClientWithEndpoint(t *testing.T) {
+	s := fakedrive.New()
+	defer s.Close()
+	ctx := context.Background()
+	if _, err := s.Drive.Create(ctx, &drive.File{Name: "report.pdf", MimeType: "application/pdf"}, nil, drive.CreateOptions{}); err != nil {
+		t.Fatalf("seeding: %v", err)
+	}
+
+	t.Setenv("DRIVEBOX_API_ENDPOINT", s.Endpoint())
+	t.Setenv("DRIVEBOX_CACHE_DIR", t.TempDir())
+	// No token.json exists here, so this only works without OAuth
+	client, err := CreateDriveClient()
+	if err != nil {
+		t.Fatalf("CreateDriveClient: %v", err)
+	}
+
+	f, err := drive.Resolve(ctx, client, "/report.pdf", "id", "name", "mimeType")
+	if err != nil {
+		t.Fatalf("Resolve: %v", err)
+	}
+	if f.MimeType != "application/pdf" {
+		t.Errorf("resolved %s with type %s, want application/pdf", f.Name, f.MimeType)
+	}
+}
@@ -0,0 +1,219 @@
+// Package cache keeps file metadata on disk so that path resolution, searches
+// and listings can be answered without walking Drive folder by folder. The
+// cache is built once with a full listing and then kept fresh through the
+// Changes API, which reports only what changed since the saved page token.
+package cache
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/zohaib-a-ahmed/drivebox/pkg/drive"
+)
+
+var (
+	// Disabled bypasses the cache (bound to the global --no-cache flag).
+	Disabled bool
+	// Account identifies the signed-in account or the API endpoint in use, so
+	// that signing in as someone else or pointing DRIVEBOX_API_ENDPOINT at
+	// another server never reads a cache built for a different drive. It is
+	// set when the Drive service is created and only stored as a hash.
+	Account string
+)
+
+// Cache is the metadata of every file in one drive, as of Token.
+type Cache struct {
+	// RootID is the real ID of the root folder that "root" stands for.
+	RootID string `json:"rootId"`
+	// Token is the Changes page token the cache is up to date with.
+	Token string `json:"pageToken"`
+	// Refreshed is when the cache was last brought up to date.
+	Refreshed time.Time `json:"refreshed"`
+	// Files holds the cached metadata by file ID.
+	Files map[string]*drive.File `json:"files"`
+
+	path string
+}
+
+// Dir returns the folder caches are stored in: DRIVEBOX_CACHE_DIR if it is
+// set in the config file, otherwise "drivebox" in the user's cache folder.
+func Dir() (string, error) {
+	if dir := os.Getenv("DRIVEBOX_CACHE_DIR"); dir != "" {
+		return dir, nil
+	}
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "drivebox"), nil
+}
+
+// Path returns the file the cache for c is stored in. My Drive and each
+// shared drive have their own cache, separately for every Account.
+func Path(c drive.Client) (string, error) {
+	dir, err := Dir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, Scope(c)+".json"), nil
+}
+
+// Scope names the drive c works in for the current Account, for use in the
+// names of files that hold state about one drive.
+func Scope(c drive.Client) string {
+	name := "mydrive"
+	if root := drive.Root(c); root != "root" {
+		name = "drive-" + root
+	}
+	sum := sha256.Sum256([]byte(Account))
+	return hex.EncodeToString(sum[:6]) + "-" + name
+}
+
+// Load reads the cache for c. It returns nil if none has been built.
+func Load(c drive.Client) (*Cache, error) {
+	path, err := Path(c)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	cache := &Cache{path: path}
+	if err := json.Unmarshal(data, cache); err != nil {
+		return nil, fmt.Errorf("cache %s is corrupt; run 'drivebox cache clear': %v", path, err)
+	}
+	if cache.Files == nil {
+		cache.Files = make(map[string]*drive.File)
+	}
+	return cache, nil
+}
+
+// Build lists every file visible through c and returns a new cache of them.
+// The page token is taken first so that nothing changed during the listing
+// is missed by the next Sync.
+func Build(ctx context.Context, c drive.Client) (*Cache, error) {
+	path, err := Path(c)
+	if err != nil {
+		return nil, err
+	}
+	token, err := c.StartPageToken(ctx, "")
+	if err != nil {
+		return nil, fmt.Errorf("failed to get a changes token: %v", err)
+	}
+	root, err := c.Get(ctx, drive.Root(c), drive.ChangeFields...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the root folder: %v", err)
+	}
+	files, err := c.List(ctx, drive.ListOptions{Fields: drive.ChangeFields})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list files: %v", err)
+	}
+
+	cache := &Cache{
+		RootID:    root.Id,
+		Token:     token,
+		Refreshed: time.Now(),
+		Files:     make(map[string]*drive.File, len(files)+1),
+		path:      path,
+	}
+	cache.Files[root.Id] = root
+	for _, f := range files {
+		cache.Files[f.Id] = f
+	}
+	return cache, nil
+}
+
+// Sync applies the changes made since the cache's page token and returns how
+// many there were.
+func (c *Cache) Sync(ctx context.Context, client drive.Client) (int, error) {
+	changes, token, err := client.Changes(ctx, c.Token, "")
+	if err != nil {
+		return 0, err
+	}
+	for _, ch := range changes {
+		if ch.ChangeType != "" && ch.ChangeType != "file" {
+			continue
+		}
+		if ch.Removed || ch.File == nil {
+			delete(c.Files, ch.FileId)
+			continue
+		}
+		c.Files[ch.FileId] = ch.File
+	}
+	if token != "" {
+		c.Token = token
+	}
+	c.Refreshed = time.Now()
+	return len(changes), nil
+}
+
+// Save writes the cache to disk, replacing the previous copy atomically.
+func (c *Cache) Save() error {
+	data, err := json.Marshal(c)
+	if err != nil {
+		return err
+	}
+	return WriteFile(c.path, data)
+}
+
+// WriteFile writes data to path through a temporary file, so a reader never
+// sees it half written, creating the folder if needed.
+func WriteFile(path string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// File returns the path the cache is stored in.
+func (c *Cache) File() string {
+	return c.path
+}
+
+// Clear removes every cache. The positions saved by 'drivebox watch' are kept.
+func Clear() error {
+	dir, err := Dir()
+	if err != nil {
+		return err
+	}
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		if strings.HasSuffix(e.Name(), ".json") && !strings.HasPrefix(e.Name(), "watch-") {
+			if err := os.Remove(filepath.Join(dir, e.Name())); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,280 @@
+package cache
+
+import (
+	"context"
+	"io"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/zohaib-a-ahmed/drivebox/pkg/drive"
+	"github.com/zohaib-a-ahmed/drivebox/pkg/query"
+)
+
+// listCounter counts the List calls that reach the wrapped client.
+type listCounter struct {
+	drive.Client
+	lists int
+}
+
+func (c *listCounter) List(ctx context.Context, opts drive.ListOptions) ([]*drive.File, error) {
+	c.lists++
+	return c.Client.List(ctx, opts)
+}
+
+// open builds and saves a cache of mem and returns a cached client for it.
+func open(t *testing.T, mem drive.Client) *Client {
+	t.Helper()
+	t.Setenv("DRIVEBOX_CACHE_DIR", t.TempDir())
+	ctx := context.Background()
+	built, err := Build(ctx, mem)
+	if err != nil {
+		t.Fatalf("Build: %v", err)
+	}
+	if err := built.Save(); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	c, err := Open(ctx, mem)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	cc, ok := c.(*Client)
+	if !ok {
+		t.Fatalf("Open returned %T, want a cached client", c)
+	}
+	return cc
+}
+
+func create(t *testing.T, c drive.Client, name, mimeType string, parents ...string) *drive.File {
+	t.Helper()
+	var media io.Reader
+	if mimeType != drive.FolderMimeType {
+		media = strings.NewReader(name)
+	}
+	f, err := c.Create(context.Background(), &drive.File{Name: name, MimeType: mimeType, Parents: parents}, media, drive.CreateOptions{})
+	if err != nil {
+		t.Fatalf("Create %s: %v", name, err)
+	}
+	return f
+}
+
+// describe lists files by name, parents and trashed state, sorted, for
+// comparing a cached listing with Drive's.
+func describe(files []*drive.File) []string {
+	var out []string
+	for _, f := range files {
+		d := f.Name + " in " + strings.Join(f.Parents, ",")
+		if f.Trashed {
+			d += " (trashed)"
+		}
+		out = append(out, d)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// compare checks that the cached client answers every query as Drive does,
+// and answers it without asking Drive.
+func compare(t *testing.T, step string, mem *drive.Memory, c *Client, counter *listCounter) {
+	t.Helper()
+	ctx := context.Background()
+	queries := []query.Query{
+		query.InParents("root"),
+		query.And(query.InParents("root"), query.Trashed(false)),
+		query.And(query.NameContains("report"), query.Trashed(false)),
+		query.And(query.NotFolder(), query.Trashed(true)),
+		query.Or(query.Name("Docs"), query.Name("Archive")),
+		query.And(query.MimeType("text/plain"), query.Trashed(false)),
+	}
+	for _, q := range queries {
+		opts := drive.ListOptions{Query: q.String(), Fields: []string{"id", "name", "parents", "trashed"}}
+		want, err := mem.List(ctx, opts)
+		if err != nil {
+			t.Fatalf("%s: Drive List(%s): %v", step, q, err)
+		}
+		before := counter.lists
+		got, err := c.List(ctx, opts)
+		if err != nil {
+			t.Fatalf("%s: cached List(%s): %v", step, q, err)
+		}
+		if counter.lists != before {
+			t.Errorf("%s: List(%s) went to Drive", step, q)
+		}
+		g, w := describe(got), describe(want)
+		if strings.Join(g, "; ") != strings.Join(w, "; ") {
+			t.Errorf("%s: List(%s)\n got %v\nwant %v", step, q, g, w)
+		}
+	}
+
+	all, err := mem.List(ctx, drive.ListOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, f := range all {
+		got, err := c.Get(ctx, f.Id, "id", "name", "parents", "trashed")
+		if err != nil {
+			t.Errorf("%s: cached Get(%s): %v", step, f.Name, err)
+			continue
+		}
+		if describe([]*drive.File{got})[0] != describe([]*drive.File{f})[0] {
+			t.Errorf("%s: cached Get(%s) = %v, want %v", step, f.Id, describe([]*drive.File{got}), describe([]*drive.File{f}))
+		}
+	}
+}
+
+func TestClientMatchesDrive(t *testing.T) {
+	ctx := context.Background()
+	mem := drive.NewMemory()
+	docs := create(t, mem, "Docs", drive.FolderMimeType)
+	archive := create(t, mem, "Archive", drive.FolderMimeType)
+	report := create(t, mem, "report.txt", "text/plain", docs.Id)
+	create(t, mem, "notes.txt", "text/plain")
+
+	counter := &listCounter{Client: mem}
+	c := open(t, counter)
+	compare(t, "after build", mem, c, counter)
+
+	create(t, c, "report-2024.txt", "text/plain", docs.Id)
+	create(t, c, "Photos", drive.FolderMimeType)
+	compare(t, "after create", mem, c, counter)
+
+	if _, err := c.Update(ctx, report.Id, &drive.File{Trashed: true}, drive.UpdateOptions{}); err != nil {
+		t.Fatalf("trash: %v", err)
+	}
+	compare(t, "after trash", mem, c, counter)
+
+	opts := drive.UpdateOptions{AddParents: []string{archive.Id}, RemoveParents: []string{docs.Id}}
+	if _, err := c.Update(ctx, report.Id, &drive.File{Name: "report-old.txt"}, opts); err != nil {
+		t.Fatalf("move: %v", err)
+	}
+	if _, err := c.Move(ctx, docs.Id, []string{archive.Id}, []string{"root"}); err != nil {
+		t.Fatalf("move folder: %v", err)
+	}
+	compare(t, "after move", mem, c, counter)
+}
+
+func TestSyncRemovals(t *testing.T) {
+	ctx := context.Background()
+	mem := drive.NewMemory()
+	docs := create(t, mem, "Docs", drive.FolderMimeType)
+	nested := create(t, mem, "nested.txt", "text/plain", docs.Id)
+	kept := create(t, mem, "kept.txt", "text/plain")
+
+	c := open(t, mem)
+	if err := c.Delete(ctx, docs.Id); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if err := c.sync(ctx); err != nil {
+		t.Fatalf("sync: %v", err)
+	}
+	for _, id := range []string{docs.Id, nested.Id} {
+		if _, ok := c.cache.Files[id]; ok {
+			t.Errorf("deleted item %s is still cached", id)
+		}
+	}
+	if _, ok := c.cache.Files[kept.Id]; !ok {
+		t.Errorf("%s was dropped from the cache", kept.Name)
+	}
+
+	// The synced cache was saved, so the next command starts from it
+	loaded, err := Load(mem)
+	if err != nil || loaded == nil {
+		t.Fatalf("Load: %v, %v", loaded, err)
+	}
+	if _, ok := loaded.Files[docs.Id]; ok || loaded.Token != c.cache.Token {
+		t.Errorf("saved cache has token %s and Docs cached %v, want token %s without Docs", loaded.Token, ok, c.cache.Token)
+	}
+}
+
+func TestResolveRoot(t *testing.T) {
+	ctx := context.Background()
+	// Drive lists children of My Drive under its real ID, not the alias
+	const rootID = "0AExampleRootFolder"
+	top := &drive.File{Id: "a", Name: "top.txt", Parents: []string{rootID}}
+	inner := &drive.File{Id: "b", Name: "inner.txt", Parents: []string{"a"}}
+	c := &Client{
+		Client: drive.NewMemory(),
+		cache: &Cache{
+			RootID: rootID,
+			Files:  map[string]*drive.File{rootID: {Id: rootID, Name: "My Drive"}, "a": top, "b": inner},
+		},
+		fields: map[string][]string{"id": nil, "name": nil, "parents": nil},
+	}
+
+	q := query.And(query.InParents("root"), query.Trashed(false)).String()
+	if got := c.resolveRoot(q); !strings.Contains(got, "'"+rootID+"' in parents") || strings.Contains(got, "'root'") {
+		t.Errorf("resolveRoot(%q) = %q", q, got)
+	}
+	files, err := c.List(ctx, drive.ListOptions{Query: query.InParents("root").String(), Fields: []string{"id", "name"}})
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if len(files) != 1 || files[0].Id != "a" {
+		t.Errorf("List of the root folder returned %v, want only top.txt", describe(files))
+	}
+	root, err := c.Get(ctx, "root", "id", "name")
+	if err != nil || root.Id != rootID {
+		t.Errorf("Get(root) = %v, %v, want the cached root folder", root, err)
+	}
+}
+
+func TestPath(t *testing.T) {
+	t.Setenv("DRIVEBOX_CACHE_DIR", t.TempDir())
+	account := Account
+	defer func() { Account = account }()
+
+	mem := drive.NewMemory()
+	Account = "https://drive.example.com/"
+	first, err := Path(mem)
+	if err != nil {
+		t.Fatal(err)
+	}
+	Account = "http://127.0.0.1:8080/"
+	second, err := Path(mem)
+	if err != nil {
+		t.Fatal(err)
+	}
+	shared, err := Path(drive.InSharedDrive(mem, "0ASharedDrive"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if first == second {
+		t.Errorf("two endpoints share the cache %s", first)
+	}
+	if second == shared || !strings.HasSuffix(shared, "-drive-0ASharedDrive.json") {
+		t.Errorf("shared drive cache is %s, My Drive cache is %s", shared, second)
+	}
+	if strings.Contains(first, "example.com") {
+		t.Errorf("the account appears in the cache file name %s", filepath.Base(first))
+	}
+}
+
+func TestCovers(t *testing.T) {
+	c := &Client{fields: make(map[string][]string)}
+	for _, f := range drive.ChangeFields {
+		name, sub := splitField(f)
+		c.fields[name] = sub
+	}
+	tests := []struct {
+		fields []string
+		want   bool
+	}{
+		{nil, true},
+		{[]string{"id", "name", "parents"}, true},
+		{[]string{"owners(emailAddress)"}, true},
+		{[]string{"owners( displayName,emailAddress )"}, true},
+		{[]string{"permissions(role, id)"}, true},
+		// The whole field, or subfields the cache does not hold, go to Drive
+		{[]string{"owners"}, false},
+		{[]string{"permissions"}, false},
+		{[]string{"permissions(id, role, emailAddress)"}, false},
+		{[]string{"id", "webViewLink"}, false},
+	}
+	for _, tt := range tests {
+		if got := c.covers(tt.fields); got != tt.want {
+			t.Errorf("covers(%q) = %v, want %v", tt.fields, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,295 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/zohaib-a-ahmed/drivebox/pkg/drive"
+	"github.com/zohaib-a-ahmed/drivebox/pkg/query"
+	"google.golang.org/api/googleapi"
+)
+
+// uncachedTerms are query terms that depend on metadata the cache does not
+// hold; queries using them go to Drive.
+var uncachedTerms = []string{"fullText", "readers", "writers", "sharedWithMe", "viewedByMeTime", "properties", "appProperties"}
+
+// sortKeys are the orderBy keys the cache can sort by.
+var sortKeys = map[string]bool{"folder": true, "name": true, "createdTime": true, "modifiedTime": true}
+
+// Client is a drive.Client that answers lookups from a Cache where it can
+// and passes everything else to the wrapped client. Changes made through it
+// mark the cache stale, so it is synced before the next lookup.
+type Client struct {
+	drive.Client
+
+	mu    sync.Mutex
+	cache *Cache
+	stale bool
+	// fields maps the cached fields to their cached subfields, or to nil
+	// when the whole field is cached.
+	fields map[string][]string
+}
+
+// Open wraps c with its cache, bringing the cache up to date first. If no
+// cache has been built or the cache is disabled, c is returned unchanged.
+func Open(ctx context.Context, c drive.Client) (drive.Client, error) {
+	if Disabled {
+		return c, nil
+	}
+	cache, err := Load(c)
+	if err != nil || cache == nil {
+		return c, err
+	}
+	cc := &Client{Client: c, cache: cache, stale: true, fields: make(map[string][]string)}
+	for _, f := range drive.ChangeFields {
+		name, sub := splitField(f)
+		cc.fields[name] = sub
+	}
+	if err := cc.sync(ctx); err != nil {
+		return c, err
+	}
+	return cc, nil
+}
+
+// Unwrap returns the client lookups fall back to.
+func (c *Client) Unwrap() drive.Client {
+	return c.Client
+}
+
+// sync brings a stale cache up to date and saves it. A page token Drive no
+// longer accepts means the cache is too old to update, so it is rebuilt.
+func (c *Client) sync(ctx context.Context) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.stale {
+		return nil
+	}
+	if _, err := c.cache.Sync(ctx, c.Client); err != nil {
+		if !isExpiredToken(err) {
+			return err
+		}
+		log.Printf("Metadata cache is out of date (%v); rebuilding it", err)
+		rebuilt, err := Build(ctx, c.Client)
+		if err != nil {
+			return err
+		}
+		c.cache = rebuilt
+	}
+	c.stale = false
+	return c.cache.Save()
+}
+
+func (c *Client) List(ctx context.Context, opts drive.ListOptions) ([]*drive.File, error) {
+	if !c.answers(opts) {
+		return c.Client.List(ctx, opts)
+	}
+	if err := c.sync(ctx); err != nil {
+		return c.Client.List(ctx, opts)
+	}
+	expr, err := query.Parse(c.resolveRoot(opts.Query))
+	if err != nil {
+		return c.Client.List(ctx, opts)
+	}
+
+	c.mu.Lock()
+	var files []*drive.File
+	for id, f := range c.cache.Files {
+		if id != c.cache.RootID && expr.Match(f) {
+			cp := *f
+			files = append(files, &cp)
+		}
+	}
+	c.mu.Unlock()
+
+	drive.SortFiles(files, opts.OrderBy)
+	if opts.Limit > 0 && len(files) > opts.Limit {
+		files = files[:opts.Limit]
+	}
+	return files, nil
+}
+
+func (c *Client) Get(ctx context.Context, id string, fields ...string) (*drive.File, error) {
+	if !c.covers(fields) {
+		return c.Client.Get(ctx, id, fields...)
+	}
+	if err := c.sync(ctx); err != nil {
+		return c.Client.Get(ctx, id, fields...)
+	}
+	c.mu.Lock()
+	if id == "root" || id == drive.Root(c.Client) {
+		id = c.cache.RootID
+	}
+	f, ok := c.cache.Files[id]
+	c.mu.Unlock()
+	if !ok {
+		return c.Client.Get(ctx, id, fields...)
+	}
+	cp := *f
+	return &cp, nil
+}
+
+func (c *Client) Create(ctx context.Context, f *drive.File, media io.Reader, opts drive.CreateOptions) (*drive.File, error) {
+	defer c.invalidate()
+	return c.Client.Create(ctx, f, media, opts)
+}
+
+func (c *Client) Update(ctx context.Context, id string, f *drive.File, opts drive.UpdateOptions) (*drive.File, error) {
+	defer c.invalidate()
+	return c.Client.Update(ctx, id, f, opts)
+}
+
+func (c *Client) Move(ctx context.Context, id string, addParents, removeParents []string) (*drive.File, error) {
+	defer c.invalidate()
+	return c.Client.Move(ctx, id, addParents, removeParents)
+}
+
+func (c *Client) Copy(ctx context.Context, id string, f *drive.File, fields ...string) (*drive.File, error) {
+	defer c.invalidate()
+	return c.Client.Copy(ctx, id, f, fields...)
+}
+
+func (c *Client) Delete(ctx context.Context, id string) error {
+	defer c.invalidate()
+	return c.Client.Delete(ctx, id)
+}
+
+func (c *Client) EmptyTrash(ctx context.Context, driveID string) error {
+	defer c.invalidate()
+	return c.Client.EmptyTrash(ctx, driveID)
+}
+
+// invalidate marks the cache stale after a change.
+func (c *Client) invalidate() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.stale = true
+}
+
+// answers reports whether the cache holds everything needed for opts.
+func (c *Client) answers(opts drive.ListOptions) bool {
+	if opts.DriveID != "" && opts.DriveID != drive.Root(c.Client) {
+		return false
+	}
+	for _, term := range uncachedTerms {
+		if strings.Contains(opts.Query, term) {
+			return false
+		}
+	}
+	for _, key := range strings.Split(opts.OrderBy, ",") {
+		if f := strings.Fields(key); len(f) > 0 && !sortKeys[f[0]] {
+			return false
+		}
+	}
+	return c.covers(opts.Fields)
+}
+
+// covers reports whether the cache holds the requested fields.
+func (c *Client) covers(fields []string) bool {
+	if len(fields) == 0 {
+		fields = drive.DefaultFields
+	}
+	for _, f := range fields {
+		name, sub := splitField(f)
+		cached, ok := c.fields[name]
+		if !ok {
+			return false
+		}
+		// Only some subfields are cached, e.g. the role but not the email
+		// address of permissions, so asking for others goes to Drive
+		if cached != nil && (sub == nil || !subset(sub, cached)) {
+			return false
+		}
+	}
+	return true
+}
+
+// resolveRoot replaces the "root" alias in q with the root folder's real ID,
+// which is what cached files list as their parent.
+func (c *Client) resolveRoot(q string) string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return strings.ReplaceAll(q, query.InParents("root").String(), query.InParents(c.cache.RootID).String())
+}
+
+// isExpiredToken reports whether err means Drive no longer accepts the
+// cache's page token: 410 Gone, or a 400 "invalid" error about the pageToken
+// parameter. Other bad requests are reported rather than rebuilding the cache.
+func isExpiredToken(err error) bool {
+	var gerr *googleapi.Error
+	if !errors.As(err, &gerr) {
+		return false
+	}
+	if gerr.Code == http.StatusGone {
+		return true
+	}
+	if gerr.Code != http.StatusBadRequest {
+		return false
+	}
+	for _, item := range gerr.Errors {
+		if item.Reason == "invalid" && mentionsPageToken(item.Message) {
+			return true
+		}
+	}
+	// Drive names the rejected parameter in the location field, which
+	// googleapi.ErrorItem drops, so look for it in the raw body
+	var body struct {
+		Error struct {
+			Errors []struct {
+				Reason   string `json:"reason"`
+				Location string `json:"location"`
+			} `json:"errors"`
+		} `json:"error"`
+	}
+	if json.Unmarshal([]byte(gerr.Body), &body) == nil {
+		for _, item := range body.Error.Errors {
+			if item.Reason == "invalid" && item.Location == "pageToken" {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// mentionsPageToken reports whether an error message is about a page token,
+// e.g. "Invalid pageToken" or "Invalid page token".
+func mentionsPageToken(msg string) bool {
+	return strings.Contains(strings.ToLower(strings.ReplaceAll(msg, " ", "")), "pagetoken")
+}
+
+// splitField splits a field into its name and selected subfields, e.g.
+// "owners(displayName, emailAddress)" into "owners" and both subfields. The
+// subfields are nil when the whole field is selected.
+func splitField(f string) (string, []string) {
+	name, rest, found := strings.Cut(f, "(")
+	if !found {
+		return strings.TrimSpace(name), nil
+	}
+	var sub []string
+	for _, s := range strings.Split(strings.TrimSuffix(strings.TrimSpace(rest), ")"), ",") {
+		sub = append(sub, strings.TrimSpace(s))
+	}
+	return strings.TrimSpace(name), sub
+}
+
+// subset reports whether every item of list is in of.
+func subset(list, of []string) bool {
+	for _, s := range list {
+		found := false
+		for _, o := range of {
+			if s == o {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,41 @@
+package cache
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"google.golang.org/api/googleapi"
+)
+
+func TestIsExpiredToken(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"gone", &googleapi.Error{Code: http.StatusGone}, true},
+		{"invalid page token", &googleapi.Error{Code: http.StatusBadRequest, Errors: []googleapi.ErrorItem{{Reason: "invalid", Message: "Invalid page token: 12"}}}, true},
+		{"invalid pageToken", &googleapi.Error{Code: http.StatusBadRequest, Errors: []googleapi.ErrorItem{{Reason: "invalid", Message: "Invalid pageToken"}}}, true},
+		{"location in body", &googleapi.Error{
+			Code:   http.StatusBadRequest,
+			Errors: []googleapi.ErrorItem{{Reason: "invalid", Message: "Invalid Value"}},
+			Body:   `{"error": {"errors": [{"domain": "global", "reason": "invalid", "message": "Invalid Value", "locationType": "parameter", "location": "pageToken"}], "code": 400, "message": "Invalid Value"}}`,
+		}, true},
+		{"wrapped", fmt.Errorf("sync: %w", &googleapi.Error{Code: http.StatusGone}), true},
+		{"other invalid parameter", &googleapi.Error{
+			Code:   http.StatusBadRequest,
+			Errors: []googleapi.ErrorItem{{Reason: "invalid", Message: "Invalid Value"}},
+			Body:   `{"error": {"errors": [{"reason": "invalid", "message": "Invalid Value", "location": "fields"}], "code": 400}}`,
+		}, false},
+		{"bad request", &googleapi.Error{Code: http.StatusBadRequest, Errors: []googleapi.ErrorItem{{Reason: "badRequest", Message: "Bad Request"}}}, false},
+		{"bare 400", &googleapi.Error{Code: http.StatusBadRequest}, false},
+		{"not found", &googleapi.Error{Code: http.StatusNotFound}, false},
+		{"other error", fmt.Errorf("connection reset"), false},
+	}
+	for _, tt := range tests {
+		if got := isExpiredToken(tt.err); got != tt.want {
+			t.Errorf("%s: isExpiredToken = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
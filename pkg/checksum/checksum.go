@@ -0,0 +1,85 @@
+package checksum
+
+import (
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"strings"
+)
+
+// Hasher computes the MD5 and SHA-256 digests of everything written to it,
+// so it can sit beside a transfer stream via io.TeeReader or io.MultiWriter.
+type Hasher struct {
+	md5    hash.Hash
+	sha256 hash.Hash
+}
+
+// New returns an empty Hasher.
+func New() *Hasher {
+	return &Hasher{md5: md5.New(), sha256: sha256.New()}
+}
+
+// Write adds p to both digests. It never returns an error.
+func (h *Hasher) Write(p []byte) (int, error) {
+	h.md5.Write(p)
+	h.sha256.Write(p)
+	return len(p), nil
+}
+
+// Reset discards everything written so far, e.g. before a retried transfer.
+func (h *Hasher) Reset() {
+	h.md5.Reset()
+	h.sha256.Reset()
+}
+
+// MD5 returns the hex-encoded MD5 digest.
+func (h *Hasher) MD5() string {
+	return hex.EncodeToString(h.md5.Sum(nil))
+}
+
+// SHA256 returns the hex-encoded SHA-256 digest.
+func (h *Hasher) SHA256() string {
+	return hex.EncodeToString(h.sha256.Sum(nil))
+}
+
+// Verify compares the computed digests against the checksums reported by
+// Drive. Empty checksums are skipped, since Drive omits them for some items.
+func (h *Hasher) Verify(md5Checksum, sha256Checksum string) error {
+	if md5Checksum != "" && !strings.EqualFold(md5Checksum, h.MD5()) {
+		return &MismatchError{Algorithm: "MD5", Expected: md5Checksum, Actual: h.MD5()}
+	}
+	if sha256Checksum != "" && !strings.EqualFold(sha256Checksum, h.SHA256()) {
+		return &MismatchError{Algorithm: "SHA-256", Expected: sha256Checksum, Actual: h.SHA256()}
+	}
+	return nil
+}
+
+// MismatchError reports that transferred content does not match Drive's checksum.
+type MismatchError struct {
+	Algorithm string
+	Expected  string
+	Actual    string
+}
+
+func (e *MismatchError) Error() string {
+	return fmt.Sprintf("%s checksum mismatch: Drive reports %s but transferred content hashes to %s", e.Algorithm, e.Expected, e.Actual)
+}
+
+// File hashes the contents of the file at path.
+func File(path string) (*Hasher, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	h := New()
+	if _, err := io.Copy(h, f); err != nil {
+		return nil, err
+	}
+	return h, nil
+}
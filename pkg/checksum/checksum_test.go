@@ -0,0 +1,82 @@
+package checksum
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const (
+	helloMD5    = "5eb63bbbe01eeed093cb22bb8f5acdc3"
+	helloSHA256 = "b94d27b9934d3e08a52e52d7da7dabfac484efe37a5380ee9088f7ace2efcde9"
+)
+
+func TestHasher(t *testing.T) {
+	h := New()
+	if _, err := io.Copy(h, strings.NewReader("hello world")); err != nil {
+		t.Fatalf("Copy: %v", err)
+	}
+	if h.MD5() != helloMD5 || h.SHA256() != helloSHA256 {
+		t.Errorf("got MD5 %s and SHA-256 %s", h.MD5(), h.SHA256())
+	}
+
+	// A retried transfer starts from scratch
+	h.Reset()
+	h.Write([]byte("hello world"))
+	if h.MD5() != helloMD5 {
+		t.Errorf("after Reset MD5 is %s, want %s", h.MD5(), helloMD5)
+	}
+}
+
+func TestVerify(t *testing.T) {
+	h := New()
+	h.Write([]byte("hello world"))
+	tests := []struct {
+		name      string
+		md5       string
+		sha256    string
+		algorithm string
+	}{
+		{"both match", helloMD5, helloSHA256, ""},
+		{"upper case", strings.ToUpper(helloMD5), strings.ToUpper(helloSHA256), ""},
+		{"only md5", helloMD5, "", ""},
+		{"only sha256", "", helloSHA256, ""},
+		{"neither reported", "", "", ""},
+		{"md5 mismatch", "00000000000000000000000000000000", helloSHA256, "MD5"},
+		{"sha256 mismatch", helloMD5, strings.Repeat("0", 64), "SHA-256"},
+		{"md5 checked first", "bad", "bad", "MD5"},
+	}
+	for _, tt := range tests {
+		err := h.Verify(tt.md5, tt.sha256)
+		var mismatch *MismatchError
+		switch {
+		case tt.algorithm == "" && err != nil:
+			t.Errorf("%s: got %v, want no error", tt.name, err)
+		case tt.algorithm != "" && !errors.As(err, &mismatch):
+			t.Errorf("%s: got %v, want a MismatchError", tt.name, err)
+		case tt.algorithm != "" && mismatch.Algorithm != tt.algorithm:
+			t.Errorf("%s: mismatch reported for %s, want %s", tt.name, mismatch.Algorithm, tt.algorithm)
+		}
+	}
+}
+
+func TestFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "hello.txt")
+	if err := os.WriteFile(path, []byte("hello world"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	h, err := File(path)
+	if err != nil {
+		t.Fatalf("File: %v", err)
+	}
+	if err := h.Verify(helloMD5, helloSHA256); err != nil {
+		t.Errorf("Verify: %v", err)
+	}
+
+	if _, err := File(filepath.Join(t.TempDir(), "missing")); err == nil {
+		t.Error("hashing a missing file succeeded")
+	}
+}
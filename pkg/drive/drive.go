@@ -0,0 +1,188 @@
+// Package drive provides a small client for the Google Drive operations used
+// by drivebox. Commands program against the Client interface so the same logic
+// runs on the real API (Google) or fully in memory (Memory).
+package drive
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+
+	gdrive "google.golang.org/api/drive/v3"
+	"google.golang.org/api/googleapi"
+)
+
+// File is the Drive v3 file resource.
+type File = gdrive.File
+
+// Change is the Drive v3 change resource.
+type Change = gdrive.Change
+
+// Permission is the Drive v3 permission resource.
+type Permission = gdrive.Permission
+
+// SharedDrive is the Drive v3 shared drive resource.
+type SharedDrive = gdrive.Drive
+
+// FolderMimeType is the MIME type Drive uses for folders.
+const FolderMimeType = "application/vnd.google-apps.folder"
+
+// DefaultFields are the file fields fetched when none are requested.
+var DefaultFields = []string{"id", "name", "mimeType", "parents", "size", "modifiedTime"}
+
+// ChangeFields are the file fields fetched with each change.
+var ChangeFields = []string{
+	"id", "name", "mimeType", "parents", "size", "md5Checksum", "modifiedTime", "createdTime",
+	"trashed", "explicitlyTrashed", "starred", "driveId", "description", "owners(displayName, emailAddress)",
+	"lastModifyingUser(displayName, emailAddress)", "permissionIds", "permissions(id, role)",
+}
+
+// Client is the set of Drive operations drivebox needs.
+type Client interface {
+	// List returns the files matching opts, following pagination.
+	List(ctx context.Context, opts ListOptions) ([]*File, error)
+	// Get returns the metadata of a single file. "root" names the My Drive root.
+	Get(ctx context.Context, id string, fields ...string) (*File, error)
+	// Create creates a file or folder, uploading media as its content if non-nil.
+	Create(ctx context.Context, f *File, media io.Reader, opts CreateOptions) (*File, error)
+	// Update patches the metadata set on f and replaces the content if opts.Media is set.
+	Update(ctx context.Context, id string, f *File, opts UpdateOptions) (*File, error)
+	// Move adds and removes parents of a file.
+	Move(ctx context.Context, id string, addParents, removeParents []string) (*File, error)
+	// Copy copies a file server-side, applying the metadata set on f (such as
+	// Name or Parents) to the copy. Folders cannot be copied.
+	Copy(ctx context.Context, id string, f *File, fields ...string) (*File, error)
+	// Download streams the content of a binary file.
+	Download(ctx context.Context, id string) (io.ReadCloser, error)
+	// Export streams a Google Workspace document converted to mimeType.
+	Export(ctx context.Context, id, mimeType string) (io.ReadCloser, error)
+	// Delete permanently deletes a file, skipping the trash.
+	Delete(ctx context.Context, id string) error
+	// EmptyTrash permanently deletes every item in the trash of My Drive, or
+	// of the shared drive driveID if it is set.
+	EmptyTrash(ctx context.Context, driveID string) error
+	// Permissions lists who has access to a file.
+	Permissions(ctx context.Context, id string) ([]*Permission, error)
+	// Share grants the permission p on a file.
+	Share(ctx context.Context, id string, p *Permission, opts ShareOptions) (*Permission, error)
+	// Unshare removes the permission permissionID from a file.
+	Unshare(ctx context.Context, id, permissionID string) error
+	// Drives lists the shared drives the user is a member of.
+	Drives(ctx context.Context) ([]*SharedDrive, error)
+	// StartPageToken returns the token from which Changes reports future
+	// changes to My Drive, or to the shared drive driveID if it is set.
+	StartPageToken(ctx context.Context, driveID string) (string, error)
+	// Changes returns every change since pageToken, following pagination,
+	// along with the token to pass next time. Changed files carry ChangeFields.
+	Changes(ctx context.Context, pageToken, driveID string) ([]*Change, string, error)
+}
+
+// ListOptions configures a List call.
+type ListOptions struct {
+	// Query is a Drive search query, e.g. "name contains 'report'".
+	Query string
+	// Fields are the file fields to return; DefaultFields if empty.
+	Fields []string
+	// OrderBy is a comma-separated list of sort keys, e.g. "folder,name".
+	OrderBy string
+	// Limit caps the number of files returned; zero returns every match.
+	Limit int
+	// DriveID searches a shared drive instead of My Drive.
+	DriveID string
+}
+
+// CreateOptions configures a Create call.
+type CreateOptions struct {
+	// Fields are the file fields to return; DefaultFields if empty.
+	Fields []string
+	// Progress is called with the bytes sent so far during resumable uploads.
+	Progress func(current, total int64)
+	// ContentType is the MIME type of the media; it is sniffed from the
+	// content if empty.
+	ContentType string
+	// OCRLanguage is an ISO 639-1 hint for OCR when an image or PDF is
+	// converted to a Google Doc.
+	OCRLanguage string
+	// ChunkSize is the size of each request of a resumable upload. Media
+	// larger than one chunk is sent in chunks of this size, so streams of
+	// unknown length need only one chunk in memory. Zero uses the default.
+	ChunkSize int
+}
+
+// UpdateOptions configures an Update call.
+type UpdateOptions struct {
+	// Fields are the file fields to return; DefaultFields if empty.
+	Fields []string
+	// Media, if set, replaces the file's content.
+	Media io.Reader
+	// AddParents and RemoveParents move the file in the same request, so a
+	// move and a rename either both happen or neither does.
+	AddParents    []string
+	RemoveParents []string
+}
+
+// Trash moves the item id, and everything inside it if it is a folder, to the trash.
+func Trash(ctx context.Context, c Client, id string) (*File, error) {
+	return c.Update(ctx, id, &File{Trashed: true}, UpdateOptions{})
+}
+
+// Restore takes the item id out of the trash.
+func Restore(ctx context.Context, c Client, id string) (*File, error) {
+	// Trashed has to be sent explicitly since false is its zero value
+	return c.Update(ctx, id, &File{Trashed: false, ForceSendFields: []string{"Trashed"}}, UpdateOptions{})
+}
+
+// ShareOptions configures a Share call.
+type ShareOptions struct {
+	// Notify emails users and groups about the new permission.
+	Notify bool
+	// Message is included in the notification email.
+	Message string
+}
+
+// PermissionFields are the permission fields fetched by Permissions.
+var PermissionFields = []string{"id", "type", "role", "emailAddress", "domain", "displayName", "expirationTime", "allowFileDiscovery"}
+
+// Grantee describes who a permission applies to, e.g. "user alice@example.com",
+// "domain example.com" or "anyone with the link".
+func Grantee(p *Permission) string {
+	switch p.Type {
+	case "anyone":
+		return "anyone with the link"
+	case "domain":
+		return "domain " + p.Domain
+	}
+	who := p.EmailAddress
+	if who == "" {
+		who = p.DisplayName
+	}
+	return p.Type + " " + who
+}
+
+// IsFolder reports whether f is a folder.
+func IsFolder(f *File) bool {
+	return f.MimeType == FolderMimeType
+}
+
+// IsWorkspace reports whether f is a Google Workspace document (Docs, Sheets,
+// Slides, ...) that has to be exported rather than downloaded.
+func IsWorkspace(f *File) bool {
+	return strings.HasPrefix(f.MimeType, "application/vnd.google-apps.") && !IsFolder(f)
+}
+
+// IsNotFound reports whether err means the requested item does not exist,
+// either because the API answered 404 or because a path did not resolve.
+func IsNotFound(err error) bool {
+	var apiErr *googleapi.Error
+	return errors.Is(err, ErrNotExist) || errors.As(err, &apiErr) && apiErr.Code == http.StatusNotFound
+}
+
+// fieldsOrDefault returns fields, or DefaultFields when fields is empty.
+func fieldsOrDefault(fields []string) []string {
+	if len(fields) == 0 {
+		return DefaultFields
+	}
+	return fields
+}
@@ -0,0 +1,259 @@
+package drive
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/zohaib-a-ahmed/drivebox/pkg/retry"
+	gdrive "google.golang.org/api/drive/v3"
+	"google.golang.org/api/googleapi"
+)
+
+// maxPageSize is the largest page Files.List will return.
+const maxPageSize = 1000
+
+// Google is a Client backed by the Drive v3 API. Calls are retried on rate
+// limits and transient errors, except uploads whose body cannot be replayed;
+// callers wrap those in retry.Do themselves.
+type Google struct {
+	svc *gdrive.Service
+}
+
+// NewGoogle returns a Client that uses svc.
+func NewGoogle(svc *gdrive.Service) *Google {
+	return &Google{svc: svc}
+}
+
+// Service returns the underlying Drive service for calls the Client does not cover.
+func (g *Google) Service() *gdrive.Service {
+	return g.svc
+}
+
+func (g *Google) List(ctx context.Context, opts ListOptions) ([]*File, error) {
+	pageSize := int64(maxPageSize)
+	if opts.Limit > 0 && opts.Limit < maxPageSize {
+		pageSize = int64(opts.Limit)
+	}
+	fields := "nextPageToken, files(" + strings.Join(fieldsOrDefault(opts.Fields), ", ") + ")"
+
+	var files []*File
+	pageToken := ""
+	for {
+		call := g.svc.Files.List().Context(ctx).
+			Q(opts.Query).
+			PageSize(pageSize).
+			SupportsAllDrives(true).
+			IncludeItemsFromAllDrives(true).
+			Fields(googleapi.Field(fields))
+		if opts.DriveID != "" {
+			call = call.Corpora("drive").DriveId(opts.DriveID)
+		}
+		if opts.OrderBy != "" {
+			call = call.OrderBy(opts.OrderBy)
+		}
+		if pageToken != "" {
+			call = call.PageToken(pageToken)
+		}
+		res, err := retry.Call(func() (*gdrive.FileList, error) { return call.Do() })
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, res.Files...)
+		if opts.Limit > 0 && len(files) >= opts.Limit {
+			return files[:opts.Limit], nil
+		}
+		if res.NextPageToken == "" {
+			return files, nil
+		}
+		pageToken = res.NextPageToken
+	}
+}
+
+func (g *Google) Get(ctx context.Context, id string, fields ...string) (*File, error) {
+	call := g.svc.Files.Get(id).Context(ctx).SupportsAllDrives(true).Fields(toFields(fieldsOrDefault(fields))...)
+	return retry.Call(func() (*File, error) { return call.Do() })
+}
+
+func (g *Google) Create(ctx context.Context, f *File, media io.Reader, opts CreateOptions) (*File, error) {
+	call := g.svc.Files.Create(f).Context(ctx).SupportsAllDrives(true).Fields(toFields(fieldsOrDefault(opts.Fields))...)
+	if media == nil {
+		return retry.Call(func() (*File, error) { return call.Do() })
+	}
+	if opts.OCRLanguage != "" {
+		call = call.OcrLanguage(opts.OCRLanguage)
+	}
+	var mediaOpts []googleapi.MediaOption
+	if opts.ContentType != "" {
+		mediaOpts = append(mediaOpts, googleapi.ContentType(opts.ContentType))
+	}
+	if opts.ChunkSize > 0 {
+		mediaOpts = append(mediaOpts, googleapi.ChunkSize(opts.ChunkSize))
+	}
+	call = call.Media(media, mediaOpts...)
+	if opts.Progress != nil {
+		call = call.ProgressUpdater(func(current, total int64) { opts.Progress(current, total) })
+	}
+	return call.Do()
+}
+
+func (g *Google) Update(ctx context.Context, id string, f *File, opts UpdateOptions) (*File, error) {
+	call := g.svc.Files.Update(id, f).Context(ctx).SupportsAllDrives(true).Fields(toFields(fieldsOrDefault(opts.Fields))...)
+	if len(opts.AddParents) > 0 {
+		call = call.AddParents(strings.Join(opts.AddParents, ","))
+	}
+	if len(opts.RemoveParents) > 0 {
+		call = call.RemoveParents(strings.Join(opts.RemoveParents, ","))
+	}
+	if opts.Media == nil {
+		return retry.Call(func() (*File, error) { return call.Do() })
+	}
+	return call.Media(opts.Media).Do()
+}
+
+func (g *Google) Move(ctx context.Context, id string, addParents, removeParents []string) (*File, error) {
+	call := g.svc.Files.Update(id, &File{}).Context(ctx).
+		AddParents(strings.Join(addParents, ",")).
+		RemoveParents(strings.Join(removeParents, ",")).
+		SupportsAllDrives(true).
+		Fields(toFields(DefaultFields)...)
+	return retry.Call(func() (*File, error) { return call.Do() })
+}
+
+func (g *Google) Copy(ctx context.Context, id string, f *File, fields ...string) (*File, error) {
+	call := g.svc.Files.Copy(id, f).Context(ctx).SupportsAllDrives(true).Fields(toFields(fieldsOrDefault(fields))...)
+	return retry.Call(func() (*File, error) { return call.Do() })
+}
+
+func (g *Google) Download(ctx context.Context, id string) (io.ReadCloser, error) {
+	resp, err := retry.Call(func() (*http.Response, error) {
+		return g.svc.Files.Get(id).Context(ctx).SupportsAllDrives(true).Download()
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+func (g *Google) Export(ctx context.Context, id, mimeType string) (io.ReadCloser, error) {
+	resp, err := retry.Call(func() (*http.Response, error) {
+		return g.svc.Files.Export(id, mimeType).Context(ctx).Download()
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+func (g *Google) Delete(ctx context.Context, id string) error {
+	return retry.Do(func() error { return g.svc.Files.Delete(id).Context(ctx).SupportsAllDrives(true).Do() })
+}
+
+func (g *Google) EmptyTrash(ctx context.Context, driveID string) error {
+	call := g.svc.Files.EmptyTrash().Context(ctx)
+	if driveID != "" {
+		call = call.DriveId(driveID)
+	}
+	return retry.Do(func() error { return call.Do() })
+}
+
+func (g *Google) StartPageToken(ctx context.Context, driveID string) (string, error) {
+	call := g.svc.Changes.GetStartPageToken().Context(ctx).SupportsAllDrives(true)
+	if driveID != "" {
+		call = call.DriveId(driveID)
+	}
+	res, err := retry.Call(func() (*gdrive.StartPageToken, error) { return call.Do() })
+	if err != nil {
+		return "", err
+	}
+	return res.StartPageToken, nil
+}
+
+func (g *Google) Changes(ctx context.Context, pageToken, driveID string) ([]*Change, string, error) {
+	fields := "nextPageToken, newStartPageToken, changes(changeType, fileId, removed, time, file(" + strings.Join(ChangeFields, ", ") + "))"
+	var changes []*Change
+	for {
+		call := g.svc.Changes.List(pageToken).Context(ctx).PageSize(1000).
+			SupportsAllDrives(true).IncludeItemsFromAllDrives(true).Fields(googleapi.Field(fields))
+		if driveID != "" {
+			call = call.DriveId(driveID)
+		}
+		res, err := retry.Call(func() (*gdrive.ChangeList, error) { return call.Do() })
+		if err != nil {
+			return nil, "", err
+		}
+		changes = append(changes, res.Changes...)
+		if res.NextPageToken == "" {
+			return changes, res.NewStartPageToken, nil
+		}
+		pageToken = res.NextPageToken
+	}
+}
+
+func (g *Google) Drives(ctx context.Context) ([]*SharedDrive, error) {
+	var drives []*SharedDrive
+	pageToken := ""
+	for {
+		call := g.svc.Drives.List().Context(ctx).PageSize(100).Fields("nextPageToken, drives(id, name, createdTime)")
+		if pageToken != "" {
+			call = call.PageToken(pageToken)
+		}
+		res, err := retry.Call(func() (*gdrive.DriveList, error) { return call.Do() })
+		if err != nil {
+			return nil, err
+		}
+		drives = append(drives, res.Drives...)
+		if res.NextPageToken == "" {
+			return drives, nil
+		}
+		pageToken = res.NextPageToken
+	}
+}
+
+func (g *Google) Permissions(ctx context.Context, id string) ([]*Permission, error) {
+	fields := "nextPageToken, permissions(" + strings.Join(PermissionFields, ", ") + ")"
+	var perms []*Permission
+	pageToken := ""
+	for {
+		call := g.svc.Permissions.List(id).Context(ctx).SupportsAllDrives(true).Fields(googleapi.Field(fields))
+		if pageToken != "" {
+			call = call.PageToken(pageToken)
+		}
+		res, err := retry.Call(func() (*gdrive.PermissionList, error) { return call.Do() })
+		if err != nil {
+			return nil, err
+		}
+		perms = append(perms, res.Permissions...)
+		if res.NextPageToken == "" {
+			return perms, nil
+		}
+		pageToken = res.NextPageToken
+	}
+}
+
+func (g *Google) Share(ctx context.Context, id string, p *Permission, opts ShareOptions) (*Permission, error) {
+	call := g.svc.Permissions.Create(id, p).Context(ctx).SupportsAllDrives(true).Fields(toFields(PermissionFields)...)
+	// Notification settings are only accepted for users and groups
+	if p.Type == "user" || p.Type == "group" {
+		call = call.SendNotificationEmail(opts.Notify)
+		if opts.Notify && opts.Message != "" {
+			call = call.EmailMessage(opts.Message)
+		}
+	}
+	return retry.Call(func() (*Permission, error) { return call.Do() })
+}
+
+func (g *Google) Unshare(ctx context.Context, id, permissionID string) error {
+	return retry.Do(func() error {
+		return g.svc.Permissions.Delete(id, permissionID).Context(ctx).SupportsAllDrives(true).Do()
+	})
+}
+
+func toFields(fields []string) []googleapi.Field {
+	out := make([]googleapi.Field, len(fields))
+	for i, f := range fields {
+		out[i] = googleapi.Field(f)
+	}
+	return out
+}
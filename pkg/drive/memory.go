@@ -0,0 +1,732 @@
+package drive
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/zohaib-a-ahmed/drivebox/pkg/query"
+	gdrive "google.golang.org/api/drive/v3"
+	"google.golang.org/api/googleapi"
+)
+
+// RootID is the ID of the My Drive root folder in a Memory client.
+const RootID = "root"
+
+// exportFormats lists the MIME types each Workspace type can be exported to,
+// as reported by the API's About.exportFormats.
+var exportFormats = map[string][]string{
+	"application/vnd.google-apps.document": {
+		"application/pdf", "application/vnd.openxmlformats-officedocument.wordprocessingml.document",
+		"application/vnd.oasis.opendocument.text", "application/rtf", "text/plain", "application/zip",
+		"application/epub+zip", "text/markdown",
+	},
+	"application/vnd.google-apps.spreadsheet": {
+		"application/pdf", "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+		"application/x-vnd.oasis.opendocument.spreadsheet", "text/csv", "text/tab-separated-values", "application/zip",
+	},
+	"application/vnd.google-apps.presentation": {
+		"application/pdf", "application/vnd.openxmlformats-officedocument.presentationml.presentation",
+		"application/vnd.oasis.opendocument.presentation", "text/plain", "image/png", "image/jpeg", "image/svg+xml",
+	},
+	"application/vnd.google-apps.drawing": {"application/pdf", "image/png", "image/jpeg", "image/svg+xml"},
+	"application/vnd.google-apps.script":  {"application/vnd.google-apps.script+json"},
+}
+
+// Memory is a Client that keeps an in-memory file tree. It is meant for tests
+// and for building on drivebox without a Google account. List queries are
+// evaluated with the query package, so they behave like the real API for the
+// fields drivebox uses.
+type Memory struct {
+	mu      sync.Mutex
+	files   map[string]*File
+	content map[string][]byte
+	nextID  int
+	changes []*Change
+	drives  map[string]*SharedDrive
+
+	// Me is recorded as the owner and last modifying user of new files.
+	Me *gdrive.User
+}
+
+// NewMemory returns an empty in-memory drive containing only the root folder.
+func NewMemory() *Memory {
+	m := &Memory{
+		files:   make(map[string]*File),
+		content: make(map[string][]byte),
+		drives:  make(map[string]*SharedDrive),
+		Me:      &gdrive.User{DisplayName: "Me", EmailAddress: "me@example.com", Me: true},
+	}
+	now := timestamp()
+	m.files[RootID] = &File{
+		Id:           RootID,
+		Name:         "My Drive",
+		MimeType:     FolderMimeType,
+		CreatedTime:  now,
+		ModifiedTime: now,
+	}
+	return m
+}
+
+func (m *Memory) List(ctx context.Context, opts ListOptions) ([]*File, error) {
+	expr, err := query.Parse(opts.Query)
+	if err != nil {
+		return nil, badRequest(err.Error())
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var files []*File
+	for id, f := range m.files {
+		// Shared drive items are only listed when searching that drive
+		if id == RootID || m.drives[id] != nil || f.DriveId != opts.DriveID || !expr.Match(f) {
+			continue
+		}
+		files = append(files, cloneFile(f))
+	}
+	SortFiles(files, opts.OrderBy)
+	if opts.Limit > 0 && len(files) > opts.Limit {
+		files = files[:opts.Limit]
+	}
+	return files, nil
+}
+
+func (m *Memory) Get(ctx context.Context, id string, fields ...string) (*File, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	f, ok := m.files[id]
+	if !ok {
+		return nil, notFound(id)
+	}
+	return cloneFile(f), nil
+}
+
+func (m *Memory) Create(ctx context.Context, f *File, media io.Reader, opts CreateOptions) (*File, error) {
+	var data []byte
+	if media != nil {
+		var err error
+		if data, err = io.ReadAll(media); err != nil {
+			return nil, err
+		}
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	parents := f.Parents
+	if len(parents) == 0 {
+		parents = []string{RootID}
+	}
+	for _, p := range parents {
+		if parent, ok := m.files[p]; !ok || !IsFolder(parent) {
+			return nil, notFound(p)
+		}
+	}
+
+	m.nextID++
+	now := timestamp()
+	nf := cloneFile(f)
+	nf.Id = fmt.Sprintf("mem%08d", m.nextID)
+	nf.Parents = append([]string(nil), parents...)
+	nf.DriveId = m.files[parents[0]].DriveId
+	nf.CreatedTime = now
+	nf.ModifiedTime = now
+	nf.Owners = []*gdrive.User{m.Me}
+	nf.Permissions = []*Permission{{
+		Id:           "owner",
+		Type:         "user",
+		Role:         "owner",
+		EmailAddress: m.Me.EmailAddress,
+		DisplayName:  m.Me.DisplayName,
+	}}
+	nf.Shared = false
+	if nf.DriveId != "" {
+		// Shared drive items belong to the drive, not to a user
+		nf.Owners = nil
+	}
+	nf.LastModifyingUser = m.Me
+	if nf.MimeType == "" && opts.ContentType != "" {
+		nf.MimeType = strings.TrimSpace(strings.SplitN(opts.ContentType, ";", 2)[0])
+	}
+	if nf.MimeType == "" {
+		if media != nil {
+			nf.MimeType = strings.SplitN(http.DetectContentType(data), ";", 2)[0]
+		} else {
+			nf.MimeType = "application/octet-stream"
+		}
+	}
+	if formats := exportFormats[nf.MimeType]; len(formats) > 0 {
+		nf.ExportLinks = make(map[string]string, len(formats))
+		for _, format := range formats {
+			nf.ExportLinks[format] = "https://docs.google.com/export?id=" + nf.Id + "&mimeType=" + format
+		}
+	}
+	m.files[nf.Id] = nf
+	if media != nil {
+		m.setContent(nf, data)
+	}
+	m.recordChange(nf)
+	if opts.Progress != nil {
+		opts.Progress(int64(len(data)), int64(len(data)))
+	}
+	return cloneFile(nf), nil
+}
+
+func (m *Memory) Update(ctx context.Context, id string, f *File, opts UpdateOptions) (*File, error) {
+	var data []byte
+	if opts.Media != nil {
+		var err error
+		if data, err = io.ReadAll(opts.Media); err != nil {
+			return nil, err
+		}
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	existing, ok := m.files[id]
+	if !ok {
+		return nil, notFound(id)
+	}
+	if len(opts.AddParents) > 0 || len(opts.RemoveParents) > 0 {
+		if err := m.move(existing, opts.AddParents, opts.RemoveParents); err != nil {
+			return nil, err
+		}
+	}
+	wasTrashed := existing.Trashed
+	patchFile(existing, f)
+	if existing.Trashed != wasTrashed {
+		existing.ExplicitlyTrashed = existing.Trashed
+		m.trashTree(id, existing.Trashed)
+	}
+	existing.ModifiedTime = timestamp()
+	existing.LastModifyingUser = m.Me
+	if opts.Media != nil {
+		m.setContent(existing, data)
+	}
+	m.recordChange(existing)
+	return cloneFile(existing), nil
+}
+
+func (m *Memory) Move(ctx context.Context, id string, addParents, removeParents []string) (*File, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	f, ok := m.files[id]
+	if !ok {
+		return nil, notFound(id)
+	}
+	if err := m.move(f, addParents, removeParents); err != nil {
+		return nil, err
+	}
+	m.recordChange(f)
+	return cloneFile(f), nil
+}
+
+// move changes the parents of f. The caller holds m.mu.
+func (m *Memory) move(f *File, addParents, removeParents []string) error {
+	if f.Id == RootID || m.drives[f.Id] != nil {
+		return notFound(f.Id)
+	}
+	for _, p := range addParents {
+		if parent, ok := m.files[p]; !ok || !IsFolder(parent) {
+			return notFound(p)
+		}
+	}
+
+	var parents []string
+	for _, p := range f.Parents {
+		if !contains(removeParents, p) {
+			parents = append(parents, p)
+		}
+	}
+	for _, p := range addParents {
+		if !contains(parents, p) {
+			parents = append(parents, p)
+		}
+	}
+	f.Parents = parents
+	if len(parents) > 0 {
+		f.DriveId = m.files[parents[0]].DriveId
+	}
+	return nil
+}
+
+func (m *Memory) Copy(ctx context.Context, id string, f *File, fields ...string) (*File, error) {
+	m.mu.Lock()
+	src, ok := m.files[id]
+	if !ok {
+		m.mu.Unlock()
+		return nil, notFound(id)
+	}
+	if IsFolder(src) {
+		m.mu.Unlock()
+		return nil, badRequest("Folders cannot be copied.")
+	}
+	meta := &File{Name: src.Name, MimeType: src.MimeType, Parents: src.Parents}
+	data := m.content[id]
+	m.mu.Unlock()
+
+	patchFile(meta, f)
+	if len(f.Parents) > 0 {
+		meta.Parents = f.Parents
+	}
+	return m.Create(ctx, meta, bytes.NewReader(data), CreateOptions{})
+}
+
+func (m *Memory) Download(ctx context.Context, id string) (io.ReadCloser, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	f, ok := m.files[id]
+	if !ok {
+		return nil, notFound(id)
+	}
+	if IsFolder(f) || IsWorkspace(f) {
+		return nil, &googleapi.Error{
+			Code:    http.StatusForbidden,
+			Message: "Only files with binary content can be downloaded. Use Export with Docs Editors files.",
+			Errors:  []googleapi.ErrorItem{{Reason: "fileNotDownloadable"}},
+		}
+	}
+	return io.NopCloser(bytes.NewReader(m.content[id])), nil
+}
+
+func (m *Memory) Export(ctx context.Context, id, mimeType string) (io.ReadCloser, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	f, ok := m.files[id]
+	if !ok {
+		return nil, notFound(id)
+	}
+	if !IsWorkspace(f) {
+		return nil, &googleapi.Error{
+			Code:    http.StatusForbidden,
+			Message: "Export only supports Docs Editors files.",
+			Errors:  []googleapi.ErrorItem{{Reason: "fileNotExportable"}},
+		}
+	}
+	if _, ok := f.ExportLinks[mimeType]; !ok {
+		return nil, badRequest("The requested conversion is not supported.")
+	}
+	// Documents are stored as-is; the export format is not simulated.
+	return io.NopCloser(bytes.NewReader(m.content[id])), nil
+}
+
+func (m *Memory) Delete(ctx context.Context, id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.files[id]; !ok || id == RootID || m.drives[id] != nil {
+		return notFound(id)
+	}
+	m.deleteTree(id)
+	return nil
+}
+
+// Permissions returns copies of the permissions on id, so callers cannot
+// change the stored file.
+func (m *Memory) Permissions(ctx context.Context, id string) ([]*Permission, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	f, ok := m.files[id]
+	if !ok {
+		return nil, notFound(id)
+	}
+	return clonePermissions(f.Permissions), nil
+}
+
+// Share grants p on the file id. A permission for the same user, group,
+// domain or anyone is updated in place, as the API does.
+func (m *Memory) Share(ctx context.Context, id string, p *Permission, opts ShareOptions) (*Permission, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	f, ok := m.files[id]
+	if !ok {
+		return nil, notFound(id)
+	}
+	switch p.Role {
+	case "reader", "commenter", "writer":
+	default:
+		return nil, badRequest("Invalid role: " + p.Role)
+	}
+	switch {
+	case (p.Type == "user" || p.Type == "group") && p.EmailAddress == "":
+		return nil, badRequest("An email address is required for " + p.Type + " permissions.")
+	case p.Type == "domain" && p.Domain == "":
+		return nil, badRequest("A domain is required for domain permissions.")
+	case p.Type != "user" && p.Type != "group" && p.Type != "domain" && p.Type != "anyone":
+		return nil, badRequest("Invalid permission type: " + p.Type)
+	case p.ExpirationTime != "" && p.Type != "user" && p.Type != "group":
+		return nil, badRequest("Expiration dates can only be set on user and group permissions.")
+	}
+
+	for _, existing := range f.Permissions {
+		if existing.Type == p.Type && existing.EmailAddress == p.EmailAddress && existing.Domain == p.Domain {
+			if existing.Role == "owner" {
+				return nil, badRequest("The owner's permission cannot be changed.")
+			}
+			existing.Role = p.Role
+			existing.ExpirationTime = p.ExpirationTime
+			m.recordChange(f)
+			c := *existing
+			return &c, nil
+		}
+	}
+	np := *p
+	if np.Type == "anyone" {
+		np.Id = "anyoneWithLink"
+	} else {
+		m.nextID++
+		np.Id = fmt.Sprintf("perm%08d", m.nextID)
+	}
+	np.ForceSendFields = nil
+	f.Permissions = append(f.Permissions, &np)
+	f.Shared = true
+	m.recordChange(f)
+	c := np
+	return &c, nil
+}
+
+func (m *Memory) Unshare(ctx context.Context, id, permissionID string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	f, ok := m.files[id]
+	if !ok {
+		return notFound(id)
+	}
+	var kept []*Permission
+	found := false
+	for _, p := range f.Permissions {
+		if p.Id != permissionID {
+			kept = append(kept, p)
+			continue
+		}
+		if p.Role == "owner" {
+			return badRequest("The owner's permission cannot be removed.")
+		}
+		found = true
+	}
+	if !found {
+		return &googleapi.Error{
+			Code:    http.StatusNotFound,
+			Message: "Permission not found: " + permissionID + ".",
+			Errors:  []googleapi.ErrorItem{{Reason: "notFound", Message: "Permission not found: " + permissionID + "."}},
+		}
+	}
+	f.Permissions = kept
+	f.Shared = len(kept) > 1
+	m.recordChange(f)
+	return nil
+}
+
+// EmptyTrash permanently deletes every trashed item in My Drive or the
+// shared drive driveID.
+func (m *Memory) EmptyTrash(ctx context.Context, driveID string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var trashed []string
+	for id, f := range m.files {
+		if f.Trashed && f.DriveId == driveID {
+			trashed = append(trashed, id)
+		}
+	}
+	for _, id := range trashed {
+		if _, ok := m.files[id]; ok {
+			m.deleteTree(id)
+		}
+	}
+	return nil
+}
+
+// AddDrive creates a shared drive called name and returns it. Its root
+// folder has the same ID as the drive, as in the real API.
+func (m *Memory) AddDrive(name string) *SharedDrive {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.nextID++
+	now := timestamp()
+	d := &SharedDrive{Id: fmt.Sprintf("drive%08d", m.nextID), Name: name, CreatedTime: now, Kind: "drive#drive"}
+	m.drives[d.Id] = d
+	m.files[d.Id] = &File{
+		Id:           d.Id,
+		Name:         name,
+		MimeType:     FolderMimeType,
+		DriveId:      d.Id,
+		CreatedTime:  now,
+		ModifiedTime: now,
+	}
+	return d
+}
+
+func (m *Memory) Drives(ctx context.Context) ([]*SharedDrive, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var drives []*SharedDrive
+	for _, d := range m.drives {
+		c := *d
+		drives = append(drives, &c)
+	}
+	sort.Slice(drives, func(i, j int) bool { return drives[i].Name < drives[j].Name })
+	return drives, nil
+}
+
+// trashTree carries a folder's trashed state to its descendants, as Drive
+// does. Restoring leaves items that were trashed on their own in the trash.
+func (m *Memory) trashTree(id string, trashed bool) {
+	for childID, f := range m.files {
+		if !contains(f.Parents, id) || f.Trashed == trashed || (!trashed && f.ExplicitlyTrashed) {
+			continue
+		}
+		f.Trashed = trashed
+		f.TrashedTime = ""
+		if trashed {
+			f.TrashedTime = timestamp()
+		}
+		m.recordChange(f)
+		m.trashTree(childID, trashed)
+	}
+}
+
+// deleteTree removes id and every item that is only reachable through it.
+func (m *Memory) deleteTree(id string) {
+	delete(m.files, id)
+	delete(m.content, id)
+	m.changes = append(m.changes, &Change{ChangeType: "file", FileId: id, Removed: true, Time: timestamp()})
+	for childID, f := range m.files {
+		if !contains(f.Parents, id) {
+			continue
+		}
+		var parents []string
+		for _, p := range f.Parents {
+			if p != id {
+				parents = append(parents, p)
+			}
+		}
+		if len(parents) == 0 {
+			m.deleteTree(childID)
+		} else {
+			f.Parents = parents
+			m.recordChange(f)
+		}
+	}
+}
+
+// StartPageToken returns the token from which Changes reports future changes.
+// Changes are numbered across all drives, so driveID does not affect it.
+func (m *Memory) StartPageToken(ctx context.Context, driveID string) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return strconv.Itoa(len(m.changes) + 1), nil
+}
+
+// Changes returns every change recorded since pageToken, along with the token
+// to pass next time. If driveID is set, only changes to items in that shared
+// drive are returned; removals are always included since the removed item's
+// drive is no longer known.
+func (m *Memory) Changes(ctx context.Context, pageToken, driveID string) ([]*Change, string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	start, err := strconv.Atoi(pageToken)
+	if err != nil || start < 1 || start > len(m.changes)+1 {
+		return nil, "", badRequest(fmt.Sprintf("Invalid page token: %s", pageToken))
+	}
+	var changes []*Change
+	for _, c := range m.changes[start-1:] {
+		if driveID != "" && c.File != nil && c.File.DriveId != driveID {
+			continue
+		}
+		cc := *c
+		if c.File != nil {
+			cc.File = cloneFile(c.File)
+			cc.File.PermissionIds = nil
+			for _, p := range cc.File.Permissions {
+				cc.File.PermissionIds = append(cc.File.PermissionIds, p.Id)
+			}
+		}
+		changes = append(changes, &cc)
+	}
+	return changes, strconv.Itoa(len(m.changes) + 1), nil
+}
+
+// About returns the current user and storage usage.
+func (m *Memory) About(ctx context.Context) (*gdrive.About, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var usage int64
+	for _, data := range m.content {
+		usage += int64(len(data))
+	}
+	return &gdrive.About{
+		User:         m.Me,
+		StorageQuota: &gdrive.AboutStorageQuota{Usage: usage, UsageInDrive: usage},
+	}, nil
+}
+
+// recordChange appends a change entry holding a snapshot of f.
+func (m *Memory) recordChange(f *File) {
+	m.changes = append(m.changes, &Change{
+		ChangeType: "file",
+		FileId:     f.Id,
+		File:       cloneFile(f),
+		Time:       timestamp(),
+	})
+}
+
+// setContent stores data as the content of f and updates its size and checksums.
+func (m *Memory) setContent(f *File, data []byte) {
+	m.content[f.Id] = data
+	if IsWorkspace(f) {
+		return
+	}
+	md5Sum := md5.Sum(data)
+	shaSum := sha256.Sum256(data)
+	f.Size = int64(len(data))
+	f.Md5Checksum = hex.EncodeToString(md5Sum[:])
+	f.Sha256Checksum = hex.EncodeToString(shaSum[:])
+}
+
+// patchFile copies the writable metadata set on src onto dst, mirroring the
+// PATCH semantics of Files.Update: zero values are ignored unless listed in
+// ForceSendFields.
+func patchFile(dst, src *File) {
+	force := func(name string) bool { return contains(src.ForceSendFields, name) }
+	if src.Name != "" {
+		dst.Name = src.Name
+	}
+	if src.Description != "" || force("Description") {
+		dst.Description = src.Description
+	}
+	if src.MimeType != "" {
+		dst.MimeType = src.MimeType
+	}
+	if src.Starred || force("Starred") {
+		dst.Starred = src.Starred
+	}
+	if src.Trashed || force("Trashed") {
+		if src.Trashed && !dst.Trashed {
+			dst.TrashedTime = timestamp()
+		}
+		dst.Trashed = src.Trashed
+		if !dst.Trashed {
+			dst.TrashedTime = ""
+		}
+	}
+	for k, v := range src.Properties {
+		if dst.Properties == nil {
+			dst.Properties = make(map[string]string)
+		}
+		dst.Properties[k] = v
+	}
+	for k, v := range src.AppProperties {
+		if dst.AppProperties == nil {
+			dst.AppProperties = make(map[string]string)
+		}
+		dst.AppProperties[k] = v
+	}
+}
+
+// SortFiles orders files by a Drive orderBy expression. Supported keys are
+// folder, name, createdTime and modifiedTime, each optionally followed by "desc".
+func SortFiles(files []*File, orderBy string) {
+	keys := strings.Split(orderBy, ",")
+	sort.SliceStable(files, func(i, j int) bool {
+		for _, key := range keys {
+			fields := strings.Fields(key)
+			if len(fields) == 0 {
+				continue
+			}
+			desc := len(fields) > 1 && fields[1] == "desc"
+			var a, b string
+			switch fields[0] {
+			case "folder":
+				a, b = boolKey(!IsFolder(files[i])), boolKey(!IsFolder(files[j]))
+			case "name":
+				a, b = strings.ToLower(files[i].Name), strings.ToLower(files[j].Name)
+			case "createdTime":
+				a, b = files[i].CreatedTime, files[j].CreatedTime
+			case "modifiedTime":
+				a, b = files[i].ModifiedTime, files[j].ModifiedTime
+			}
+			if a != b {
+				return (a < b) != desc
+			}
+		}
+		return files[i].Id < files[j].Id
+	})
+}
+
+func boolKey(b bool) string {
+	if b {
+		return "1"
+	}
+	return "0"
+}
+
+// cloneFile copies f deeply enough that callers cannot modify stored state.
+func cloneFile(f *File) *File {
+	c := *f
+	c.Parents = append([]string(nil), f.Parents...)
+	c.ForceSendFields = nil
+	c.NullFields = nil
+	if f.Properties != nil {
+		c.Properties = make(map[string]string, len(f.Properties))
+		for k, v := range f.Properties {
+			c.Properties[k] = v
+		}
+	}
+	if f.AppProperties != nil {
+		c.AppProperties = make(map[string]string, len(f.AppProperties))
+		for k, v := range f.AppProperties {
+			c.AppProperties[k] = v
+		}
+	}
+	c.Permissions = clonePermissions(f.Permissions)
+	return &c
+}
+
+func clonePermissions(perms []*Permission) []*Permission {
+	if perms == nil {
+		return nil
+	}
+	out := make([]*Permission, len(perms))
+	for i, p := range perms {
+		c := *p
+		out[i] = &c
+	}
+	return out
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+func timestamp() string {
+	return time.Now().UTC().Format(time.RFC3339Nano)
+}
+
+func notFound(id string) error {
+	return &googleapi.Error{
+		Code:    http.StatusNotFound,
+		Message: fmt.Sprintf("File not found: %s.", id),
+		Errors:  []googleapi.ErrorItem{{Reason: "notFound", Message: fmt.Sprintf("File not found: %s.", id)}},
+	}
+}
+
+func badRequest(msg string) error {
+	return &googleapi.Error{
+		Code:    http.StatusBadRequest,
+		Message: msg,
+		Errors:  []googleapi.ErrorItem{{Reason: "invalid", Message: msg}},
+	}
+}
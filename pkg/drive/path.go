@@ -0,0 +1,326 @@
+package drive
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/zohaib-a-ahmed/drivebox/pkg/query"
+)
+
+// ErrNotExist is returned when a path names an item that does not exist.
+var ErrNotExist = errors.New("no such file or folder")
+
+// IDPrefix marks an item reference as a Drive ID rather than a path, e.g. "id:1AbC".
+const IDPrefix = "id:"
+
+// SplitPath splits a slash-separated Drive path into its names. Leading,
+// trailing and repeated slashes are ignored, so "/a//b/" is ["a", "b"].
+func SplitPath(p string) []string {
+	var names []string
+	for _, name := range strings.Split(p, "/") {
+		if name != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// Lookup returns the item referenced by ref, which is either "id:<ID>" or a
+// path from the My Drive root such as "/Backups/db.sql.gz".
+func Lookup(ctx context.Context, c Client, ref string, fields ...string) (*File, error) {
+	if id, ok := strings.CutPrefix(ref, IDPrefix); ok {
+		return c.Get(ctx, id, fields...)
+	}
+	return Resolve(ctx, c, ref, fields...)
+}
+
+// Resolve walks a path from the My Drive root and returns the item it names.
+// Trashed items are ignored. It fails if any component is missing or if a
+// folder holds more than one item with the same name.
+func Resolve(ctx context.Context, c Client, p string, fields ...string) (*File, error) {
+	names := SplitPath(p)
+	if len(names) == 0 {
+		return c.Get(ctx, Root(c), fields...)
+	}
+
+	parentID := Root(c)
+	var current *File
+	for i, name := range names {
+		f, err := Child(ctx, c, parentID, name, fields...)
+		if err != nil {
+			return nil, err
+		}
+		if f == nil {
+			return nil, fmt.Errorf("%s: %w", "/"+path.Join(names[:i+1]...), ErrNotExist)
+		}
+		if i < len(names)-1 && !IsFolder(f) {
+			return nil, fmt.Errorf("%s: not a folder", "/"+path.Join(names[:i+1]...))
+		}
+		current = f
+		parentID = f.Id
+	}
+	return current, nil
+}
+
+// Child returns the untrashed item called name directly inside the folder
+// parentID, or nil if there is none. Duplicate names are reported as an error
+// since a path cannot tell them apart.
+func Child(ctx context.Context, c Client, parentID, name string, fields ...string) (*File, error) {
+	q := query.And(query.Name(name), query.InParents(parentID), query.Trashed(false))
+	files, err := c.List(ctx, ListOptions{Query: q.String(), Fields: withIDFields(fields), Limit: 2})
+	if err != nil {
+		return nil, err
+	}
+	switch len(files) {
+	case 0:
+		return nil, nil
+	case 1:
+		return files[0], nil
+	}
+	return nil, fmt.Errorf("%q is ambiguous: more than one item with that name exists in the same folder; use %s<ID> instead", name, IDPrefix)
+}
+
+// Children lists the untrashed items directly inside the folder parentID.
+func Children(ctx context.Context, c Client, parentID string, fields ...string) ([]*File, error) {
+	q := query.And(query.InParents(parentID), query.Trashed(false))
+	return c.List(ctx, ListOptions{Query: q.String(), Fields: withIDFields(fields), OrderBy: "folder,name"})
+}
+
+// withIDFields makes sure the fields needed to keep walking a path are fetched.
+func withIDFields(fields []string) []string {
+	fields = fieldsOrDefault(fields)
+	for _, required := range []string{"id", "name", "mimeType"} {
+		if !contains(fields, required) {
+			fields = append(fields, required)
+		}
+	}
+	return fields
+}
+
+// Destination interprets the destination of a move or copy. If dest is an
+// existing folder, it is returned with an empty name and items keep their
+// names. Otherwise dest names a new item: its parent folder is returned along
+// with the new name, which is only allowed for a single source.
+func Destination(ctx context.Context, c Client, dest string, multiple bool) (*File, string, error) {
+	fields := []string{"id", "name", "mimeType", "parents"}
+	target, err := Lookup(ctx, c, dest, fields...)
+	if err == nil {
+		if !IsFolder(target) {
+			return nil, "", fmt.Errorf("%s already exists and is not a folder", dest)
+		}
+		return target, "", nil
+	}
+	if strings.HasPrefix(dest, IDPrefix) || !errors.Is(err, ErrNotExist) {
+		return nil, "", err
+	}
+
+	if multiple {
+		return nil, "", fmt.Errorf("%s is not an existing folder; several items need a folder destination", dest)
+	}
+	names := SplitPath(dest)
+	parentPath := "/" + path.Join(names[:len(names)-1]...)
+	parent, err := Resolve(ctx, c, parentPath, fields...)
+	if err != nil {
+		return nil, "", err
+	}
+	if !IsFolder(parent) {
+		return nil, "", fmt.Errorf("%s: not a folder", parentPath)
+	}
+	return parent, names[len(names)-1], nil
+}
+
+// IsWithin reports whether folder is the item id or one of its descendants,
+// following first parents. It guards against moving or copying a folder into
+// itself.
+func IsWithin(ctx context.Context, c Client, folder *File, id string) (bool, error) {
+	current := folder
+	for depth := 0; depth < maxDepth; depth++ {
+		if current.Id == id {
+			return true, nil
+		}
+		if len(current.Parents) == 0 {
+			return false, nil
+		}
+		parent, err := c.Get(ctx, current.Parents[0], "id", "parents")
+		if err != nil {
+			if IsNotFound(err) {
+				return false, nil
+			}
+			return false, err
+		}
+		current = parent
+	}
+	return false, nil
+}
+
+// PathFinder computes the full paths of items, caching the folders it visits
+// so that paths for many items in the same tree cost few requests.
+type PathFinder struct {
+	c       Client
+	rootID  string
+	folders map[string]*File
+}
+
+// NewPathFinder returns a PathFinder that looks up folders through c.
+func NewPathFinder(c Client) *PathFinder {
+	return &PathFinder{c: c, folders: make(map[string]*File)}
+}
+
+// Remember caches any folders among files so later lookups need no requests.
+func (p *PathFinder) Remember(files ...*File) {
+	for _, f := range files {
+		if IsFolder(f) {
+			p.folders[f.Id] = f
+		}
+	}
+}
+
+// Path returns the path of f from the My Drive root, e.g. "/Work/report.pdf",
+// following its first parent. Items outside My Drive (such as files shared
+// with you) have no root, so their path starts at the highest visible folder.
+func (p *PathFinder) Path(ctx context.Context, f *File) (string, error) {
+	paths, err := p.paths(ctx, f, 1)
+	if err != nil {
+		return "", err
+	}
+	return paths[0], nil
+}
+
+// Paths returns every path of f, one per parent.
+func (p *PathFinder) Paths(ctx context.Context, f *File) ([]string, error) {
+	return p.paths(ctx, f, -1)
+}
+
+func (p *PathFinder) paths(ctx context.Context, f *File, max int) ([]string, error) {
+	if p.rootID == "" {
+		root, err := p.c.Get(ctx, Root(p.c), "id")
+		if err != nil {
+			return nil, err
+		}
+		p.rootID = root.Id
+	}
+	if f.Id == p.rootID {
+		return []string{"/"}, nil
+	}
+	if len(f.Parents) == 0 {
+		return []string{f.Name}, nil
+	}
+
+	var out []string
+	for i, parentID := range f.Parents {
+		if max > 0 && i >= max {
+			break
+		}
+		prefix, err := p.folderPath(ctx, parentID, 0)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, strings.TrimSuffix(prefix, "/")+"/"+f.Name)
+	}
+	return out, nil
+}
+
+// maxDepth guards against cycles in malformed parent chains.
+const maxDepth = 100
+
+func (p *PathFinder) folderPath(ctx context.Context, id string, depth int) (string, error) {
+	if id == p.rootID {
+		return "/", nil
+	}
+	folder, ok := p.folders[id]
+	if !ok {
+		var err error
+		folder, err = p.c.Get(ctx, id, "id", "name", "parents")
+		if err != nil {
+			if IsNotFound(err) {
+				// The parent exists but is not visible to us.
+				return "", nil
+			}
+			return "", err
+		}
+		p.folders[id] = folder
+	}
+	if len(folder.Parents) == 0 || depth >= maxDepth {
+		return folder.Name, nil
+	}
+	prefix, err := p.folderPath(ctx, folder.Parents[0], depth+1)
+	if err != nil {
+		return "", err
+	}
+	if prefix == "" {
+		return folder.Name, nil
+	}
+	return strings.TrimSuffix(prefix, "/") + "/" + folder.Name, nil
+}
+
+// Mkdir creates the folder named by path p, whose parent folder must already
+// exist. It fails if the parent already holds an item with the same name.
+func Mkdir(ctx context.Context, c Client, p string, fields ...string) (*File, error) {
+	names := SplitPath(p)
+	if len(names) == 0 {
+		return nil, fmt.Errorf("%q: a folder name is required", p)
+	}
+	parent, err := Resolve(ctx, c, "/"+path.Join(names[:len(names)-1]...), "id", "mimeType")
+	if err != nil {
+		return nil, err
+	}
+	if !IsFolder(parent) {
+		return nil, fmt.Errorf("%s: not a folder", "/"+path.Join(names[:len(names)-1]...))
+	}
+	name := names[len(names)-1]
+	existing, err := Child(ctx, c, parent.Id, name, "id")
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil {
+		return nil, fmt.Errorf("%s: already exists", "/"+path.Join(names...))
+	}
+	return createFolder(ctx, c, parent.Id, name, fields)
+}
+
+// MkdirAll creates the folder named by path p along with any missing parents,
+// like "mkdir -p". Folders that already exist are reused, so it succeeds if
+// the whole path exists; it fails if a component exists but is not a folder.
+func MkdirAll(ctx context.Context, c Client, p string, fields ...string) (*File, error) {
+	names := SplitPath(p)
+	if len(names) == 0 {
+		return c.Get(ctx, Root(c), fields...)
+	}
+
+	parentID := Root(c)
+	var current *File
+	for i, name := range names {
+		f, err := EnsureFolder(ctx, c, parentID, name, fields...)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", "/"+path.Join(names[:i+1]...), err)
+		}
+		current = f
+		parentID = f.Id
+	}
+	return current, nil
+}
+
+// EnsureFolder returns the folder called name inside parentID, creating it
+// if it does not exist. It fails if name exists but is not a folder.
+func EnsureFolder(ctx context.Context, c Client, parentID, name string, fields ...string) (*File, error) {
+	f, err := Child(ctx, c, parentID, name, fields...)
+	if err != nil {
+		return nil, err
+	}
+	if f == nil {
+		return createFolder(ctx, c, parentID, name, fields)
+	}
+	if !IsFolder(f) {
+		return nil, errors.New("exists and is not a folder")
+	}
+	return f, nil
+}
+
+// createFolder creates a folder called name inside parentID.
+func createFolder(ctx context.Context, c Client, parentID, name string, fields []string) (*File, error) {
+	folder := &File{Name: name, MimeType: FolderMimeType, Parents: []string{parentID}}
+	return c.Create(ctx, folder, nil, CreateOptions{Fields: withIDFields(fields)})
+}
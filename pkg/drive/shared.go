@@ -0,0 +1,107 @@
+package drive
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Scoped is a Client confined to one shared drive. Searches cover only that
+// drive, "root" names the drive's root folder and new items without parents
+// are created at its top level, so paths resolve from the shared drive root.
+type Scoped struct {
+	Client
+	// DriveID is the ID of the shared drive, which is also the ID of its root folder.
+	DriveID string
+}
+
+// InSharedDrive returns a Client that works inside the shared drive driveID.
+func InSharedDrive(c Client, driveID string) *Scoped {
+	return &Scoped{Client: c, DriveID: driveID}
+}
+
+// Root returns the ID of the folder that paths start from for c: the alias
+// "root" for My Drive, or the shared drive's ID for a Scoped client. Clients
+// that wrap another one are seen through with an Unwrap method.
+func Root(c Client) string {
+	for {
+		switch w := c.(type) {
+		case *Scoped:
+			return w.DriveID
+		case interface{ Unwrap() Client }:
+			c = w.Unwrap()
+		default:
+			return "root"
+		}
+	}
+}
+
+func (s *Scoped) List(ctx context.Context, opts ListOptions) ([]*File, error) {
+	if opts.DriveID == "" {
+		opts.DriveID = s.DriveID
+	}
+	return s.Client.List(ctx, opts)
+}
+
+func (s *Scoped) Get(ctx context.Context, id string, fields ...string) (*File, error) {
+	if id == "root" {
+		id = s.DriveID
+	}
+	return s.Client.Get(ctx, id, fields...)
+}
+
+func (s *Scoped) Create(ctx context.Context, f *File, media io.Reader, opts CreateOptions) (*File, error) {
+	if len(f.Parents) == 0 {
+		scoped := *f
+		scoped.Parents = []string{s.DriveID}
+		f = &scoped
+	}
+	return s.Client.Create(ctx, f, media, opts)
+}
+
+func (s *Scoped) EmptyTrash(ctx context.Context, driveID string) error {
+	if driveID == "" {
+		driveID = s.DriveID
+	}
+	return s.Client.EmptyTrash(ctx, driveID)
+}
+
+func (s *Scoped) StartPageToken(ctx context.Context, driveID string) (string, error) {
+	if driveID == "" {
+		driveID = s.DriveID
+	}
+	return s.Client.StartPageToken(ctx, driveID)
+}
+
+func (s *Scoped) Changes(ctx context.Context, pageToken, driveID string) ([]*Change, string, error) {
+	if driveID == "" {
+		driveID = s.DriveID
+	}
+	return s.Client.Changes(ctx, pageToken, driveID)
+}
+
+// FindDrive returns the shared drive whose ID or name is ref. Names must be
+// unique among the user's shared drives.
+func FindDrive(ctx context.Context, c Client, ref string) (*SharedDrive, error) {
+	drives, err := c.Drives(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list shared drives: %v", err)
+	}
+	var matches []*SharedDrive
+	for _, d := range drives {
+		if d.Id == ref {
+			return d, nil
+		}
+		if strings.EqualFold(d.Name, ref) {
+			matches = append(matches, d)
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return nil, fmt.Errorf("no shared drive named %q; see 'drivebox drives list'", ref)
+	case 1:
+		return matches[0], nil
+	}
+	return nil, fmt.Errorf("%d shared drives are named %q; use the drive ID instead", len(matches), ref)
+}
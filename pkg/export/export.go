@@ -0,0 +1,151 @@
+// Package export chooses the formats Google Workspace documents are
+// converted to when they are downloaded. Docs, Sheets, Slides and the other
+// Workspace types cannot be downloaded as-is and must be exported instead.
+package export
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/zohaib-a-ahmed/drivebox/pkg/drive"
+)
+
+// Format is a file format a document can be exported to.
+type Format struct {
+	// Name is the short name used on the command line, e.g. "docx".
+	Name string
+	// MimeType is the MIME type requested from Files.Export.
+	MimeType string
+	// Extension is appended to the exported file's name.
+	Extension string
+}
+
+// Formats lists every export format drivebox knows, by name.
+var Formats = map[string]Format{
+	"pdf":  {"pdf", "application/pdf", ".pdf"},
+	"docx": {"docx", "application/vnd.openxmlformats-officedocument.wordprocessingml.document", ".docx"},
+	"odt":  {"odt", "application/vnd.oasis.opendocument.text", ".odt"},
+	"rtf":  {"rtf", "application/rtf", ".rtf"},
+	"txt":  {"txt", "text/plain", ".txt"},
+	"html": {"html", "application/zip", ".zip"}, // zipped web page
+	"epub": {"epub", "application/epub+zip", ".epub"},
+	"md":   {"md", "text/markdown", ".md"},
+	"xlsx": {"xlsx", "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet", ".xlsx"},
+	"ods":  {"ods", "application/x-vnd.oasis.opendocument.spreadsheet", ".ods"},
+	"csv":  {"csv", "text/csv", ".csv"},
+	"tsv":  {"tsv", "text/tab-separated-values", ".tsv"},
+	"pptx": {"pptx", "application/vnd.openxmlformats-officedocument.presentationml.presentation", ".pptx"},
+	"odp":  {"odp", "application/vnd.oasis.opendocument.presentation", ".odp"},
+	"png":  {"png", "image/png", ".png"},
+	"jpg":  {"jpg", "image/jpeg", ".jpg"},
+	"svg":  {"svg", "image/svg+xml", ".svg"},
+	"json": {"json", "application/vnd.google-apps.script+json", ".json"},
+}
+
+// defaults are the built-in formats for each Workspace type, used when
+// neither the command line nor the config file chooses one.
+var defaults = map[string]string{
+	"document":     "pdf",
+	"spreadsheet":  "xlsx",
+	"presentation": "pptx",
+	"drawing":      "png",
+	"script":       "json",
+}
+
+// Choose returns the formats to export f to. Requested formats come from the
+// command line and apply to every item, so formats f cannot be exported to
+// are skipped with a warning; if none of them suit f, or none were
+// requested, the configured default for f's type is used instead. Unknown
+// format names are reported as an error.
+func Choose(f *drive.File, requested []string) ([]Format, error) {
+	if len(requested) > 0 {
+		formats, unsupported, err := lookup(f, requested)
+		if err != nil {
+			return nil, err
+		}
+		if len(unsupported) == 0 {
+			return formats, nil
+		}
+		if len(formats) > 0 {
+			log.Printf("%s cannot be exported as %s; skipping (available formats: %s)", f.Name, strings.Join(unsupported, ", "), strings.Join(Available(f), ", "))
+			return formats, nil
+		}
+		log.Printf("%s cannot be exported as %s; using its default format instead (available formats: %s)", f.Name, strings.Join(unsupported, ", "), strings.Join(Available(f), ", "))
+	}
+
+	names := Default(f.MimeType)
+	if len(names) == 0 {
+		return nil, fmt.Errorf("%s cannot be exported: %s has no export format", f.Name, f.MimeType)
+	}
+	formats, unsupported, err := lookup(f, names)
+	if err != nil {
+		return nil, err
+	}
+	if len(unsupported) > 0 {
+		return nil, fmt.Errorf("%s cannot be exported as %s; available formats: %s", f.Name, strings.Join(unsupported, ", "), strings.Join(Available(f), ", "))
+	}
+	return formats, nil
+}
+
+// lookup resolves format names, splitting them into the formats f can be
+// exported to and the names of those it cannot.
+func lookup(f *drive.File, names []string) (formats []Format, unsupported []string, err error) {
+	for _, name := range names {
+		format, ok := Formats[strings.ToLower(strings.TrimPrefix(name, "."))]
+		if !ok {
+			return nil, nil, fmt.Errorf("unknown export format %q", name)
+		}
+		if !Supports(f, format) {
+			unsupported = append(unsupported, format.Name)
+			continue
+		}
+		formats = append(formats, format)
+	}
+	return formats, unsupported, nil
+}
+
+// Default returns the default format names for a Workspace MIME type. They
+// can be set in the config file as DRIVEBOX_EXPORT_<TYPE>, for example
+// DRIVEBOX_EXPORT_DOCUMENT=docx,pdf.
+func Default(mimeType string) []string {
+	kind := strings.TrimPrefix(mimeType, "application/vnd.google-apps.")
+	if v := os.Getenv("DRIVEBOX_EXPORT_" + strings.ToUpper(kind)); v != "" {
+		var names []string
+		for _, name := range strings.Split(v, ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				names = append(names, strings.ToLower(name))
+			}
+		}
+		return names
+	}
+	if name, ok := defaults[kind]; ok {
+		return []string{name}
+	}
+	return nil
+}
+
+// Supports reports whether f can be exported to format, according to its
+// exportLinks. Files fetched without exportLinks are assumed to support it
+// and left for the API to reject.
+func Supports(f *drive.File, format Format) bool {
+	if len(f.ExportLinks) == 0 {
+		return true
+	}
+	_, ok := f.ExportLinks[format.MimeType]
+	return ok
+}
+
+// Available returns the names of the formats f can be exported to.
+func Available(f *drive.File) []string {
+	var names []string
+	for name, format := range Formats {
+		if _, ok := f.ExportLinks[format.MimeType]; ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
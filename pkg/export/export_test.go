@@ -0,0 +1,64 @@
+package export
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/zohaib-a-ahmed/drivebox/pkg/drive"
+)
+
+// file returns a Workspace file whose exportLinks list formats.
+func file(mimeType string, formats ...string) *drive.File {
+	links := make(map[string]string)
+	for _, name := range formats {
+		links[Formats[name].MimeType] = "https://example.com/" + name
+	}
+	return &drive.File{Name: "item", MimeType: "application/vnd.google-apps." + mimeType, ExportLinks: links}
+}
+
+func TestChoose(t *testing.T) {
+	doc := file("document", "pdf", "docx", "txt")
+	sheet := file("spreadsheet", "pdf", "xlsx", "csv")
+	slides := file("presentation", "pdf", "pptx")
+	tests := []struct {
+		name      string
+		f         *drive.File
+		requested []string
+		want      []string
+	}{
+		{"default", doc, nil, []string{"pdf"}},
+		{"requested", doc, []string{"docx", "txt"}, []string{"docx", "txt"}},
+		{"case and dot", doc, []string{".DOCX"}, []string{"docx"}},
+		{"unsupported skipped", sheet, []string{"docx", "csv"}, []string{"csv"}},
+		{"none supported", sheet, []string{"docx"}, []string{"xlsx"}},
+		{"none supported for slides", slides, []string{"docx", "csv"}, []string{"pptx"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			formats, err := Choose(tt.f, tt.requested)
+			if err != nil {
+				t.Fatalf("Choose: %v", err)
+			}
+			var got []string
+			for _, format := range formats {
+				got = append(got, format.Name)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestChooseErrors(t *testing.T) {
+	if _, err := Choose(file("document", "pdf"), []string{"pdf", "bogus"}); err == nil {
+		t.Error("an unknown format name should be an error")
+	}
+	if _, err := Choose(file("form"), nil); err == nil {
+		t.Error("a type without a default format should be an error")
+	}
+	t.Setenv("DRIVEBOX_EXPORT_DOCUMENT", "xlsx")
+	if _, err := Choose(file("document", "pdf"), []string{"csv"}); err == nil {
+		t.Error("a configured default the item cannot take should be an error")
+	}
+}
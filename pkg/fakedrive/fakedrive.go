@@ -0,0 +1,400 @@
+// Package fakedrive serves the subset of the Drive v3 REST API used by
+// drivebox from an in-memory tree, so commands can be exercised end to end
+// without network access or a Google account.
+//
+// Point a Drive service at it with Server.Service, or point the drivebox CLI
+// at it by setting DRIVEBOX_API_ENDPOINT to Server.Endpoint().
+package fakedrive
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode"
+
+	"github.com/zohaib-a-ahmed/drivebox/pkg/drive"
+	gdrive "google.golang.org/api/drive/v3"
+	"google.golang.org/api/googleapi"
+	"google.golang.org/api/option"
+)
+
+const (
+	apiPrefix    = "/drive/v3/"
+	uploadPrefix = "/upload/drive/v3/"
+	// defaultPageSize matches the API's default for files.list.
+	defaultPageSize = 100
+)
+
+// Server is a fake Drive API backed by a drive.Memory tree.
+type Server struct {
+	*httptest.Server
+
+	// Drive holds the files served by the fake. Tests may seed it directly.
+	Drive *drive.Memory
+
+	mu       sync.Mutex
+	sessions map[string]*session
+	nextID   int
+	failures []failure
+}
+
+// failure is an injected error returned instead of handling a request.
+type failure struct {
+	status     int
+	reason     string
+	retryAfter string
+}
+
+// New starts a fake Drive server with an empty tree. Call Close when done.
+func New() *Server {
+	s := &Server{
+		Drive:    drive.NewMemory(),
+		sessions: make(map[string]*session),
+	}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serveHTTP))
+	return s
+}
+
+// Endpoint returns the base URL to pass to option.WithEndpoint.
+func (s *Server) Endpoint() string {
+	return s.URL + apiPrefix
+}
+
+// Service returns a Drive service that talks to the fake server.
+func (s *Server) Service(ctx context.Context) (*gdrive.Service, error) {
+	return gdrive.NewService(ctx,
+		option.WithEndpoint(s.Endpoint()),
+		option.WithHTTPClient(s.Client()),
+	)
+}
+
+// FailNext makes the next count requests fail with the given HTTP status and
+// error reason, e.g. 403 "rateLimitExceeded". A non-empty retryAfter is sent
+// as the Retry-After header.
+func (s *Server) FailNext(count, status int, reason, retryAfter string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i := 0; i < count; i++ {
+		s.failures = append(s.failures, failure{status: status, reason: reason, retryAfter: retryAfter})
+	}
+}
+
+func (s *Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	if len(s.failures) > 0 {
+		f := s.failures[0]
+		s.failures = s.failures[1:]
+		s.mu.Unlock()
+		if f.retryAfter != "" {
+			w.Header().Set("Retry-After", f.retryAfter)
+		}
+		writeError(w, &googleapi.Error{
+			Code:    f.status,
+			Message: "Injected failure",
+			Errors:  []googleapi.ErrorItem{{Reason: f.reason, Message: "Injected failure"}},
+		})
+		return
+	}
+	s.mu.Unlock()
+
+	ctx := r.Context()
+	switch {
+	case strings.HasPrefix(r.URL.Path, uploadPrefix):
+		s.serveUpload(ctx, w, r, strings.TrimPrefix(r.URL.Path, uploadPrefix))
+	case strings.HasPrefix(r.URL.Path, apiPrefix):
+		s.serveAPI(ctx, w, r, strings.TrimPrefix(r.URL.Path, apiPrefix))
+	default:
+		writeError(w, notFoundPath(r.URL.Path))
+	}
+}
+
+func (s *Server) serveAPI(ctx context.Context, w http.ResponseWriter, r *http.Request, path string) {
+	parts := strings.Split(path, "/")
+	switch {
+	case path == "about" && r.Method == http.MethodGet:
+		writeResult(w)(s.Drive.About(ctx))
+
+	case path == "changes/startPageToken" && r.Method == http.MethodGet:
+		token, err := s.Drive.StartPageToken(ctx, r.URL.Query().Get("driveId"))
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		writeJSON(w, &gdrive.StartPageToken{Kind: "drive#startPageToken", StartPageToken: token})
+
+	case path == "changes" && r.Method == http.MethodGet:
+		changes, newToken, err := s.Drive.Changes(ctx, r.URL.Query().Get("pageToken"), r.URL.Query().Get("driveId"))
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		writeJSON(w, &gdrive.ChangeList{Kind: "drive#changeList", Changes: changes, NewStartPageToken: newToken})
+
+	case path == "drives" && r.Method == http.MethodGet:
+		drives, err := s.Drive.Drives(ctx)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		writeJSON(w, &gdrive.DriveList{Kind: "drive#driveList", Drives: drives})
+
+	case path == "files" && r.Method == http.MethodGet:
+		s.listFiles(ctx, w, r)
+
+	case path == "files" && r.Method == http.MethodPost:
+		meta, err := decodeFile(r.Body)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		writeResult(w)(s.Drive.Create(ctx, meta, nil, drive.CreateOptions{}))
+
+	case path == "files/trash" && r.Method == http.MethodDelete:
+		if err := s.Drive.EmptyTrash(ctx, r.URL.Query().Get("driveId")); err != nil {
+			writeError(w, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+
+	case len(parts) == 2 && parts[0] == "files":
+		s.serveFile(ctx, w, r, parts[1])
+
+	case len(parts) == 3 && parts[0] == "files" && parts[2] == "permissions" && r.Method == http.MethodGet:
+		perms, err := s.Drive.Permissions(ctx, parts[1])
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		writeJSON(w, &gdrive.PermissionList{Kind: "drive#permissionList", Permissions: perms})
+
+	case len(parts) == 3 && parts[0] == "files" && parts[2] == "permissions" && r.Method == http.MethodPost:
+		var perm drive.Permission
+		if err := json.NewDecoder(r.Body).Decode(&perm); err != nil {
+			writeError(w, badRequest("Invalid permission: "+err.Error()))
+			return
+		}
+		opts := drive.ShareOptions{
+			Notify:  r.URL.Query().Get("sendNotificationEmail") != "false",
+			Message: r.URL.Query().Get("emailMessage"),
+		}
+		writeResult(w)(s.Drive.Share(ctx, parts[1], &perm, opts))
+
+	case len(parts) == 4 && parts[0] == "files" && parts[2] == "permissions" && r.Method == http.MethodDelete:
+		if err := s.Drive.Unshare(ctx, parts[1], parts[3]); err != nil {
+			writeError(w, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+
+	case len(parts) == 3 && parts[0] == "files" && parts[2] == "copy" && r.Method == http.MethodPost:
+		meta, err := decodeFile(r.Body)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		writeResult(w)(s.Drive.Copy(ctx, parts[1], meta))
+
+	case len(parts) == 3 && parts[0] == "files" && parts[2] == "export" && r.Method == http.MethodGet:
+		body, err := s.Drive.Export(ctx, parts[1], r.URL.Query().Get("mimeType"))
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		defer body.Close()
+		w.Header().Set("Content-Type", r.URL.Query().Get("mimeType"))
+		io.Copy(w, body)
+
+	default:
+		writeError(w, notFoundPath(r.URL.Path))
+	}
+}
+
+func (s *Server) listFiles(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+	params := r.URL.Query()
+	opts := drive.ListOptions{Query: params.Get("q"), OrderBy: params.Get("orderBy")}
+	if params.Get("corpora") == "drive" {
+		opts.DriveID = params.Get("driveId")
+	}
+	files, err := s.Drive.List(ctx, opts)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	pageSize := defaultPageSize
+	if v := params.Get("pageSize"); v != "" {
+		if pageSize, err = strconv.Atoi(v); err != nil || pageSize < 1 {
+			writeError(w, badRequest("Invalid pageSize: "+v))
+			return
+		}
+	}
+	// Page tokens are simply offsets into the sorted result set.
+	offset := 0
+	if v := params.Get("pageToken"); v != "" {
+		if offset, err = strconv.Atoi(v); err != nil || offset < 0 || offset > len(files) {
+			writeError(w, badRequest("Invalid pageToken: "+v))
+			return
+		}
+	}
+
+	res := &gdrive.FileList{Kind: "drive#fileList", Files: files[offset:]}
+	if end := offset + pageSize; end < len(files) {
+		res.Files = files[offset:end]
+		res.NextPageToken = strconv.Itoa(end)
+	}
+	writeJSON(w, res)
+}
+
+func (s *Server) serveFile(ctx context.Context, w http.ResponseWriter, r *http.Request, id string) {
+	params := r.URL.Query()
+	switch r.Method {
+	case http.MethodGet:
+		if params.Get("alt") == "media" {
+			body, err := s.Drive.Download(ctx, id)
+			if err != nil {
+				writeError(w, err)
+				return
+			}
+			defer body.Close()
+			io.Copy(w, body)
+			return
+		}
+		writeResult(w)(s.Drive.Get(ctx, id))
+
+	case http.MethodPatch:
+		meta, err := decodeFile(r.Body)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		s.updateFile(ctx, w, id, meta, params, nil)
+
+	case http.MethodDelete:
+		if err := s.Drive.Delete(ctx, id); err != nil {
+			writeError(w, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		writeError(w, notFoundPath(r.URL.Path))
+	}
+}
+
+// updateFile applies a files.update request: parent changes, metadata and
+// optional new content, as one change.
+func (s *Server) updateFile(ctx context.Context, w http.ResponseWriter, id string, meta *drive.File, params map[string][]string, media io.Reader) {
+	writeResult(w)(s.Drive.Update(ctx, id, meta, drive.UpdateOptions{
+		Media:         media,
+		AddParents:    splitList(first(params["addParents"])),
+		RemoveParents: splitList(first(params["removeParents"])),
+	}))
+}
+
+// decodeFile decodes file metadata from a request body. Fields present in the
+// JSON are added to ForceSendFields so that explicit false or empty values
+// (e.g. "trashed": false) are applied by the PATCH semantics of drive.Memory.
+func decodeFile(r io.Reader) (*drive.File, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	f := &drive.File{}
+	if len(strings.TrimSpace(string(data))) == 0 {
+		return f, nil
+	}
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, badRequest("Invalid JSON payload: " + err.Error())
+	}
+	if err := json.Unmarshal(data, f); err != nil {
+		return nil, badRequest("Invalid JSON payload: " + err.Error())
+	}
+	for key := range raw {
+		if key == "" {
+			continue
+		}
+		runes := []rune(key)
+		runes[0] = unicode.ToUpper(runes[0])
+		f.ForceSendFields = append(f.ForceSendFields, string(runes))
+	}
+	return f, nil
+}
+
+func writeResult(w http.ResponseWriter) func(v any, err error) {
+	return func(v any, err error) {
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		writeJSON(w, v)
+	}
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	json.NewEncoder(w).Encode(v)
+}
+
+// writeError writes err in the error envelope the Google API client parses.
+func writeError(w http.ResponseWriter, err error) {
+	var apiErr *googleapi.Error
+	if !errors.As(err, &apiErr) {
+		apiErr = &googleapi.Error{Code: http.StatusInternalServerError, Message: err.Error()}
+	}
+	type item struct {
+		Reason  string `json:"reason,omitempty"`
+		Message string `json:"message,omitempty"`
+	}
+	body := struct {
+		Error struct {
+			Code    int    `json:"code"`
+			Message string `json:"message"`
+			Errors  []item `json:"errors,omitempty"`
+		} `json:"error"`
+	}{}
+	body.Error.Code = apiErr.Code
+	body.Error.Message = apiErr.Message
+	for _, e := range apiErr.Errors {
+		body.Error.Errors = append(body.Error.Errors, item{Reason: e.Reason, Message: e.Message})
+	}
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	w.WriteHeader(apiErr.Code)
+	json.NewEncoder(w).Encode(body)
+}
+
+func notFoundPath(path string) error {
+	return &googleapi.Error{Code: http.StatusNotFound, Message: fmt.Sprintf("No handler for %s", path)}
+}
+
+func badRequest(msg string) error {
+	return &googleapi.Error{
+		Code:    http.StatusBadRequest,
+		Message: msg,
+		Errors:  []googleapi.ErrorItem{{Reason: "invalid", Message: msg}},
+	}
+}
+
+func first(values []string) string {
+	if len(values) == 0 {
+		return ""
+	}
+	return values[0]
+}
+
+func splitList(s string) []string {
+	var out []string
+	for _, v := range strings.Split(s, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			out = append(out, v)
+		}
+	}
+	return out
+}
@@ -0,0 +1,317 @@
+package fakedrive
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/zohaib-a-ahmed/drivebox/pkg/drive"
+	"github.com/zohaib-a-ahmed/drivebox/pkg/query"
+	"google.golang.org/api/googleapi"
+)
+
+// newClient starts a fake server and returns a drive.Google client talking
+// to it, along with a counter of the requests made to each URL path.
+func newClient(t *testing.T) (*Server, *drive.Google, func(path string) int) {
+	t.Helper()
+	s := New()
+	t.Cleanup(s.Close)
+
+	var mu sync.Mutex
+	requests := make(map[string]int)
+	handler := s.Config.Handler
+	s.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requests[r.URL.Path]++
+		mu.Unlock()
+		handler.ServeHTTP(w, r)
+	})
+
+	svc, err := s.Service(context.Background())
+	if err != nil {
+		t.Fatalf("Service: %v", err)
+	}
+	count := func(path string) int {
+		mu.Lock()
+		defer mu.Unlock()
+		return requests[path]
+	}
+	return s, drive.NewGoogle(svc), count
+}
+
+func TestListPaging(t *testing.T) {
+	s, c, count := newClient(t)
+	ctx := context.Background()
+	const n = 2500
+	for i := 0; i < n; i++ {
+		if _, err := s.Drive.Create(ctx, &drive.File{Name: fmt.Sprintf("file-%04d", i)}, nil, drive.CreateOptions{}); err != nil {
+			t.Fatalf("seeding: %v", err)
+		}
+	}
+
+	files, err := c.List(ctx, drive.ListOptions{Query: query.InParents("root").String(), OrderBy: "name"})
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if len(files) != n {
+		t.Fatalf("List returned %d files, want %d", len(files), n)
+	}
+	for i, f := range files {
+		if want := fmt.Sprintf("file-%04d", i); f.Name != want {
+			t.Fatalf("files[%d] = %q, want %q", i, f.Name, want)
+		}
+	}
+	if got := count(apiPrefix + "files"); got != 3 {
+		t.Errorf("List made %d requests, want 3 pages", got)
+	}
+
+	limited, err := c.List(ctx, drive.ListOptions{Query: query.InParents("root").String(), Limit: 1500})
+	if err != nil {
+		t.Fatalf("List with limit: %v", err)
+	}
+	if len(limited) != 1500 {
+		t.Errorf("List with limit returned %d files, want 1500", len(limited))
+	}
+}
+
+func TestCreate(t *testing.T) {
+	tests := []struct {
+		name      string
+		size      int
+		chunkSize int
+		resumable bool
+	}{
+		{"multipart", 1000, 0, false},
+		{"resumable", 600 << 10, googleapi.MinUploadChunkSize, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, c, _ := newClient(t)
+			ctx := context.Background()
+			data := bytes.Repeat([]byte("drivebox"), tt.size/8)
+
+			f, err := c.Create(ctx, &drive.File{Name: "upload.bin"}, bytes.NewReader(data), drive.CreateOptions{
+				ContentType: "application/octet-stream",
+				ChunkSize:   tt.chunkSize,
+				Fields:      []string{"id", "name", "size", "md5Checksum"},
+			})
+			if err != nil {
+				t.Fatalf("Create: %v", err)
+			}
+			if f.Name != "upload.bin" || f.Size != int64(len(data)) {
+				t.Errorf("created %q of %d bytes, want upload.bin of %d", f.Name, f.Size, len(data))
+			}
+			if resumable := s.nextID > 0; resumable != tt.resumable {
+				t.Errorf("resumable session used = %v, want %v", resumable, tt.resumable)
+			}
+
+			body, err := c.Download(ctx, f.Id)
+			if err != nil {
+				t.Fatalf("Download: %v", err)
+			}
+			defer body.Close()
+			got, err := io.ReadAll(body)
+			if err != nil {
+				t.Fatalf("reading download: %v", err)
+			}
+			if !bytes.Equal(got, data) {
+				t.Errorf("downloaded %d bytes that differ from the %d uploaded", len(got), len(data))
+			}
+		})
+	}
+}
+
+func TestExport(t *testing.T) {
+	_, c, _ := newClient(t)
+	ctx := context.Background()
+	doc, err := c.Create(ctx, &drive.File{Name: "Notes", MimeType: "application/vnd.google-apps.document"}, strings.NewReader("hello"), drive.CreateOptions{})
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+
+	body, err := c.Export(ctx, doc.Id, "application/pdf")
+	if err != nil {
+		t.Fatalf("Export: %v", err)
+	}
+	got, _ := io.ReadAll(body)
+	body.Close()
+	if string(got) != "hello" {
+		t.Errorf("Export returned %q, want %q", got, "hello")
+	}
+
+	if _, err := c.Export(ctx, doc.Id, "application/x-unknown"); !hasCode(err, http.StatusBadRequest) {
+		t.Errorf("Export to an unsupported format: got %v, want HTTP 400", err)
+	}
+	file, err := c.Create(ctx, &drive.File{Name: "plain.txt"}, strings.NewReader("text"), drive.CreateOptions{})
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if _, err := c.Export(ctx, file.Id, "application/pdf"); !hasCode(err, http.StatusForbidden) {
+		t.Errorf("Export of a binary file: got %v, want HTTP 403", err)
+	}
+}
+
+func TestDelete(t *testing.T) {
+	_, c, _ := newClient(t)
+	ctx := context.Background()
+	folder, err := c.Create(ctx, &drive.File{Name: "Folder", MimeType: drive.FolderMimeType}, nil, drive.CreateOptions{})
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	child, err := c.Create(ctx, &drive.File{Name: "child.txt", Parents: []string{folder.Id}}, strings.NewReader("x"), drive.CreateOptions{})
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+
+	if err := c.Delete(ctx, folder.Id); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	for _, id := range []string{folder.Id, child.Id} {
+		if _, err := c.Get(ctx, id); !drive.IsNotFound(err) {
+			t.Errorf("Get(%s) after deleting its folder: got %v, want not found", id, err)
+		}
+	}
+	if err := c.Delete(ctx, folder.Id); !drive.IsNotFound(err) {
+		t.Errorf("deleting twice: got %v, want not found", err)
+	}
+}
+
+func TestChanges(t *testing.T) {
+	_, c, _ := newClient(t)
+	ctx := context.Background()
+	token, err := c.StartPageToken(ctx, "")
+	if err != nil {
+		t.Fatalf("StartPageToken: %v", err)
+	}
+
+	f, err := c.Create(ctx, &drive.File{Name: "a.txt"}, strings.NewReader("a"), drive.CreateOptions{})
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if _, err := c.Update(ctx, f.Id, &drive.File{Name: "b.txt"}, drive.UpdateOptions{}); err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	if err := c.Delete(ctx, f.Id); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+
+	changes, next, err := c.Changes(ctx, token, "")
+	if err != nil {
+		t.Fatalf("Changes: %v", err)
+	}
+	if len(changes) != 3 {
+		t.Fatalf("got %d changes, want 3", len(changes))
+	}
+	if changes[1].File == nil || changes[1].File.Name != "b.txt" {
+		t.Errorf("second change is %+v, want the rename to b.txt", changes[1].File)
+	}
+	if last := changes[2]; !last.Removed || last.FileId != f.Id {
+		t.Errorf("last change is %+v, want the removal of %s", last, f.Id)
+	}
+	if next == "" || next == token {
+		t.Errorf("new start page token %q should move past %q", next, token)
+	}
+
+	changes, _, err = c.Changes(ctx, next, "")
+	if err != nil {
+		t.Fatalf("Changes: %v", err)
+	}
+	if len(changes) != 0 {
+		t.Errorf("got %d changes after the new token, want none", len(changes))
+	}
+}
+
+func TestRetries(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("rate limited then succeeds", func(t *testing.T) {
+		s, c, count := newClient(t)
+		s.FailNext(1, http.StatusForbidden, "userRateLimitExceeded", "")
+		if _, err := c.Get(ctx, "root"); err != nil {
+			t.Fatalf("Get: %v", err)
+		}
+		if got := count(apiPrefix + "files/root"); got != 2 {
+			t.Errorf("made %d requests, want 2", got)
+		}
+	})
+
+	t.Run("server error then succeeds", func(t *testing.T) {
+		s, c, count := newClient(t)
+		s.FailNext(1, http.StatusServiceUnavailable, "backendError", "")
+		if _, err := c.List(ctx, drive.ListOptions{Query: query.InParents("root").String()}); err != nil {
+			t.Fatalf("List: %v", err)
+		}
+		if got := count(apiPrefix + "files"); got != 2 {
+			t.Errorf("made %d requests, want 2", got)
+		}
+	})
+
+	t.Run("not retried", func(t *testing.T) {
+		s, c, count := newClient(t)
+		s.FailNext(1, http.StatusForbidden, "insufficientFilePermissions", "")
+		if _, err := c.Get(ctx, "root"); !hasCode(err, http.StatusForbidden) {
+			t.Fatalf("Get: got %v, want HTTP 403", err)
+		}
+		if got := count(apiPrefix + "files/root"); got != 1 {
+			t.Errorf("made %d requests, want 1", got)
+		}
+	})
+
+	t.Run("attempts exhausted", func(t *testing.T) {
+		t.Setenv("DRIVEBOX_MAX_RETRIES", "1")
+		s, c, count := newClient(t)
+		s.FailNext(3, http.StatusTooManyRequests, "rateLimitExceeded", "")
+		if _, err := c.Get(ctx, "root"); !hasCode(err, http.StatusTooManyRequests) {
+			t.Fatalf("Get: got %v, want HTTP 429", err)
+		}
+		if got := count(apiPrefix + "files/root"); got != 2 {
+			t.Errorf("made %d requests, want 2", got)
+		}
+	})
+}
+
+func hasCode(err error, code int) bool {
+	var apiErr *googleapi.Error
+	return errors.As(err, &apiErr) && apiErr.Code == code
+}
+
+func TestUpdateMovesAndRenames(t *testing.T) {
+	_, c, count := newClient(t)
+	ctx := context.Background()
+	folder, err := c.Create(ctx, &drive.File{Name: "Archive", MimeType: drive.FolderMimeType}, nil, drive.CreateOptions{})
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	f, err := c.Create(ctx, &drive.File{Name: "draft.txt"}, strings.NewReader("x"), drive.CreateOptions{Fields: []string{"id", "parents"}})
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+
+	moved, err := c.Update(ctx, f.Id, &drive.File{Name: "final.txt"}, drive.UpdateOptions{
+		Fields:        []string{"id", "name", "parents"},
+		AddParents:    []string{folder.Id},
+		RemoveParents: f.Parents,
+	})
+	if err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	if moved.Name != "final.txt" || len(moved.Parents) != 1 || moved.Parents[0] != folder.Id {
+		t.Errorf("got %s in %v, want final.txt in [%s]", moved.Name, moved.Parents, folder.Id)
+	}
+	if got := count(apiPrefix + "files/" + f.Id); got != 1 {
+		t.Errorf("made %d requests, want 1", got)
+	}
+
+	if _, err := c.Update(ctx, f.Id, &drive.File{Name: "lost.txt"}, drive.UpdateOptions{AddParents: []string{"missing"}}); !drive.IsNotFound(err) {
+		t.Fatalf("moving into a missing folder: got %v, want not found", err)
+	}
+	if got, err := c.Get(ctx, f.Id, "name"); err != nil || got.Name != "final.txt" {
+		t.Errorf("after a failed move the name is %v (%v), want it unchanged", got, err)
+	}
+}
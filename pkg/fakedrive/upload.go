@@ -0,0 +1,188 @@
+package fakedrive
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/zohaib-a-ahmed/drivebox/pkg/drive"
+)
+
+// session is an in-progress resumable upload.
+type session struct {
+	fileID      string // empty when creating a new file
+	meta        *drive.File
+	params      url.Values
+	contentType string
+	data        bytes.Buffer
+}
+
+// serveUpload handles the /upload endpoints: simple, multipart and resumable
+// uploads for files.create and files.update, plus resumable session chunks.
+func (s *Server) serveUpload(ctx context.Context, w http.ResponseWriter, r *http.Request, path string) {
+	if strings.HasPrefix(path, "sessions/") {
+		s.uploadChunk(ctx, w, r, strings.TrimPrefix(path, "sessions/"))
+		return
+	}
+
+	var fileID string
+	switch {
+	case path == "files" && r.Method == http.MethodPost:
+	case strings.HasPrefix(path, "files/") && r.Method == http.MethodPatch:
+		fileID = strings.TrimPrefix(path, "files/")
+	default:
+		writeError(w, notFoundPath(r.URL.Path))
+		return
+	}
+
+	params := r.URL.Query()
+	var (
+		meta        = &drive.File{}
+		media       io.Reader
+		contentType = r.Header.Get("Content-Type")
+		err         error
+	)
+	switch params.Get("uploadType") {
+	case "media":
+		media = r.Body
+	case "multipart":
+		meta, media, contentType, err = readMultipart(r)
+	case "resumable":
+		if meta, err = decodeFile(r.Body); err != nil {
+			break
+		}
+		s.mu.Lock()
+		s.nextID++
+		id := strconv.Itoa(s.nextID)
+		s.sessions[id] = &session{fileID: fileID, meta: meta, params: params, contentType: r.Header.Get("X-Upload-Content-Type")}
+		s.mu.Unlock()
+		w.Header().Set("Location", s.URL+uploadPrefix+"sessions/"+id)
+		w.WriteHeader(http.StatusOK)
+		return
+	default:
+		err = badRequest("Unsupported uploadType: " + params.Get("uploadType"))
+	}
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	s.finishUpload(ctx, w, fileID, meta, params, media, contentType)
+}
+
+// uploadChunk appends a chunk to a resumable session. Until the final chunk
+// arrives it answers with the "resume incomplete" signal the client expects.
+func (s *Server) uploadChunk(ctx context.Context, w http.ResponseWriter, r *http.Request, id string) {
+	s.mu.Lock()
+	sess, ok := s.sessions[id]
+	s.mu.Unlock()
+	if !ok {
+		writeError(w, notFoundPath(r.URL.Path))
+		return
+	}
+
+	start, total, err := parseContentRange(r.Header.Get("Content-Range"))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if start >= 0 && start != int64(sess.data.Len()) {
+		writeError(w, badRequest(fmt.Sprintf("Chunk starts at %d, expected %d", start, sess.data.Len())))
+		return
+	}
+	if _, err := io.Copy(&sess.data, r.Body); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	if total < 0 || int64(sess.data.Len()) < total {
+		if sess.data.Len() > 0 {
+			w.Header().Set("Range", fmt.Sprintf("bytes=0-%d", sess.data.Len()-1))
+		}
+		w.Header().Set("X-Http-Status-Code-Override", "308")
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	s.mu.Lock()
+	delete(s.sessions, id)
+	s.mu.Unlock()
+	s.finishUpload(ctx, w, sess.fileID, sess.meta, sess.params, &sess.data, sess.contentType)
+}
+
+// finishUpload creates or updates the file once all of its content is known.
+func (s *Server) finishUpload(ctx context.Context, w http.ResponseWriter, fileID string, meta *drive.File, params url.Values, media io.Reader, contentType string) {
+	if fileID != "" {
+		s.updateFile(ctx, w, fileID, meta, params, media)
+		return
+	}
+	opts := drive.CreateOptions{ContentType: contentType, OCRLanguage: params.Get("ocrLanguage")}
+	writeResult(w)(s.Drive.Create(ctx, meta, media, opts))
+}
+
+// readMultipart splits a multipart/related upload into metadata and content,
+// returning the content type of the media part.
+func readMultipart(r *http.Request) (*drive.File, io.Reader, string, error) {
+	mediaType, params, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil || !strings.HasPrefix(mediaType, "multipart/") {
+		return nil, nil, "", badRequest("Multipart upload requires a multipart Content-Type")
+	}
+	mr := multipart.NewReader(r.Body, params["boundary"])
+
+	part, err := mr.NextPart()
+	if err != nil {
+		return nil, nil, "", badRequest("Missing metadata part: " + err.Error())
+	}
+	meta, err := decodeFile(part)
+	if err != nil {
+		return nil, nil, "", err
+	}
+
+	part, err = mr.NextPart()
+	if err != nil {
+		return nil, nil, "", badRequest("Missing media part: " + err.Error())
+	}
+	data, err := io.ReadAll(part)
+	if err != nil {
+		return nil, nil, "", err
+	}
+	return meta, bytes.NewReader(data), part.Header.Get("Content-Type"), nil
+}
+
+// parseContentRange parses "bytes 0-99/*", "bytes 0-99/100" and "bytes */100".
+// start is -1 when the request carries no data; total is -1 when unknown.
+func parseContentRange(v string) (start, total int64, err error) {
+	invalid := badRequest("Invalid Content-Range: " + v)
+	spec, ok := strings.CutPrefix(v, "bytes ")
+	if !ok {
+		return 0, 0, invalid
+	}
+	rng, size, ok := strings.Cut(spec, "/")
+	if !ok {
+		return 0, 0, invalid
+	}
+
+	total = -1
+	if size != "*" {
+		if total, err = strconv.ParseInt(size, 10, 64); err != nil {
+			return 0, 0, invalid
+		}
+	}
+	start = -1
+	if rng != "*" {
+		from, _, ok := strings.Cut(rng, "-")
+		if !ok {
+			return 0, 0, invalid
+		}
+		if start, err = strconv.ParseInt(from, 10, 64); err != nil {
+			return 0, 0, invalid
+		}
+	}
+	return start, total, nil
+}
@@ -0,0 +1,52 @@
+// Package format renders the dates and sizes shown in command output and
+// parses the dates given on the command line.
+package format
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/zohaib-a-ahmed/drivebox/pkg/drive"
+	"github.com/zohaib-a-ahmed/drivebox/pkg/progress"
+)
+
+// Layouts for Time.
+const (
+	Date   = "2006-01-02"
+	Minute = "2006-01-02 15:04"
+	Second = "2006-01-02 15:04:05"
+)
+
+// Time renders an RFC 3339 timestamp from Drive in local time using layout.
+// Anything that does not parse is returned unchanged.
+func Time(s, layout string) string {
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return s
+	}
+	return t.Local().Format(layout)
+}
+
+// ParseDate accepts a plain date, taken as local midnight, or a full RFC 3339 timestamp.
+func ParseDate(s string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	t, err := time.ParseInLocation(Date, s, time.Local)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid date %q: expected YYYY-MM-DD or RFC 3339", s)
+	}
+	return t, nil
+}
+
+// Size renders the size of f for listings. Folders and Google Workspace
+// files take no storage, so they are shown as "folder" and "-".
+func Size(f *drive.File) string {
+	if drive.IsFolder(f) {
+		return "folder"
+	}
+	if drive.IsWorkspace(f) {
+		return "-"
+	}
+	return progress.FormatBytes(f.Size)
+}
@@ -0,0 +1,238 @@
+// Package ignore decides which files recursive transfers skip. Patterns
+// follow gitignore semantics and come from .driveboxignore files and the
+// --include and --exclude flags.
+package ignore
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// FileName is the name of the files holding ignore patterns. Patterns in a
+// .driveboxignore apply to the folder it is in and everything below it.
+const FileName = ".driveboxignore"
+
+// rule is one parsed gitignore pattern.
+type rule struct {
+	re *regexp.Regexp
+	// base is the slash-separated folder the pattern is relative to.
+	base string
+	// negate re-includes matches, written with a leading "!".
+	negate bool
+	// dirOnly only matches folders, written with a trailing "/".
+	dirOnly bool
+}
+
+// Matcher reports whether paths are ignored. Paths are slash-separated and
+// relative to the root of the transfer. The zero value ignores nothing.
+type Matcher struct {
+	// rules come from ignore files, in the order they were loaded.
+	rules []rule
+	// excludes and includes come from the command line.
+	excludes []rule
+	includes []rule
+}
+
+// New returns a Matcher for the --include and --exclude flags. Excludes are
+// gitignore patterns and take precedence over ignore files. If there are
+// includes, only files matching one of them are kept; folders are always
+// walked so files inside them can match.
+func New(include, exclude []string) (*Matcher, error) {
+	m := &Matcher{}
+	for _, p := range include {
+		r, ok, err := parse(p, "")
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			m.includes = append(m.includes, r)
+		}
+	}
+	for _, p := range exclude {
+		r, ok, err := parse(p, "")
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			m.excludes = append(m.excludes, r)
+		}
+	}
+	return m, nil
+}
+
+// Add adds a gitignore pattern relative to the folder base. Later patterns
+// take precedence, so a "!" pattern can re-include what an earlier one
+// ignored. Blank lines and comments are skipped.
+func (m *Matcher) Add(pattern, base string) error {
+	r, ok, err := parse(pattern, base)
+	if err != nil || !ok {
+		return err
+	}
+	m.rules = append(m.rules, r)
+	return nil
+}
+
+// Load adds the patterns of the ignore file in the local folder dir, whose
+// path relative to the transfer root is base. A missing file is not an error.
+func (m *Matcher) Load(dir, base string) error {
+	f, err := os.Open(filepath.Join(dir, FileName))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return m.Read(f, base)
+}
+
+// Read adds the patterns read from an ignore file in the folder base.
+func (m *Matcher) Read(r io.Reader, base string) error {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		if err := m.Add(scanner.Text(), base); err != nil {
+			return fmt.Errorf("%s: %v", path.Join(base, FileName), err)
+		}
+	}
+	return scanner.Err()
+}
+
+// Ignored reports whether the item at the relative path p is skipped. Items
+// inside an ignored folder are not checked, since the folder is never walked.
+func (m *Matcher) Ignored(p string, isDir bool) bool {
+	if m == nil {
+		return false
+	}
+	ignored := false
+	for _, rules := range [][]rule{m.rules, m.excludes} {
+		for _, r := range rules {
+			if r.matches(p, isDir) {
+				ignored = !r.negate
+			}
+		}
+	}
+	if ignored || isDir || len(m.includes) == 0 {
+		return ignored
+	}
+	for _, r := range m.includes {
+		if r.matches(p, isDir) {
+			return false
+		}
+	}
+	return true
+}
+
+// Clone returns a copy of m that patterns can be added to without changing m,
+// for ignore files that only apply to one branch of a tree.
+func (m *Matcher) Clone() *Matcher {
+	if m == nil {
+		return &Matcher{}
+	}
+	return &Matcher{
+		rules:    append([]rule(nil), m.rules...),
+		excludes: m.excludes,
+		includes: m.includes,
+	}
+}
+
+func (r rule) matches(p string, isDir bool) bool {
+	if r.dirOnly && !isDir {
+		return false
+	}
+	if r.base != "" {
+		rest, ok := strings.CutPrefix(p, r.base+"/")
+		if !ok {
+			return false
+		}
+		p = rest
+	}
+	return r.re.MatchString(p)
+}
+
+// parse compiles a gitignore pattern. It returns false for blank lines and
+// comments.
+func parse(pattern, base string) (rule, bool, error) {
+	p := strings.TrimRight(pattern, " \t\r")
+	if p == "" || strings.HasPrefix(p, "#") {
+		return rule{}, false, nil
+	}
+	r := rule{base: strings.Trim(base, "/")}
+	if strings.HasPrefix(p, "!") {
+		r.negate = true
+		p = p[1:]
+	} else if strings.HasPrefix(p, `\#`) || strings.HasPrefix(p, `\!`) {
+		p = p[1:]
+	}
+	if strings.HasSuffix(p, "/") {
+		r.dirOnly = true
+		p = strings.TrimRight(p, "/")
+	}
+	if p == "" {
+		return rule{}, false, nil
+	}
+
+	// A slash anywhere but the end anchors the pattern to its base folder;
+	// otherwise it matches a name at any depth.
+	anchored := strings.Contains(p, "/")
+	p = strings.TrimPrefix(p, "/")
+	expr := globToRegexp(p)
+	if !anchored {
+		expr = "(?:.*/)?" + expr
+	}
+	re, err := regexp.Compile("^" + expr + "$")
+	if err != nil {
+		return rule{}, false, fmt.Errorf("invalid pattern %q: %v", pattern, err)
+	}
+	r.re = re
+	return r, true, nil
+}
+
+// globToRegexp translates a glob to a regular expression. "*" and "?" stop at
+// slashes, "**" spans any number of folders and [...] is a character class.
+func globToRegexp(glob string) string {
+	var sb strings.Builder
+	for i := 0; i < len(glob); i++ {
+		c := glob[i]
+		switch {
+		case strings.HasPrefix(glob[i:], "**/"):
+			// Zero or more folders
+			sb.WriteString("(?:.*/)?")
+			i += 2
+		case strings.HasPrefix(glob[i:], "/**") && i+3 == len(glob):
+			// Everything inside
+			sb.WriteString("/.*")
+			i += 2
+		case strings.HasPrefix(glob[i:], "**"):
+			sb.WriteString(".*")
+			i++
+		case c == '*':
+			sb.WriteString("[^/]*")
+		case c == '?':
+			sb.WriteString("[^/]")
+		case c == '\\' && i+1 < len(glob):
+			i++
+			sb.WriteString(regexp.QuoteMeta(string(glob[i])))
+		case c == '[':
+			end := strings.IndexByte(glob[i+1:], ']')
+			if end < 0 {
+				sb.WriteString(`\[`)
+				continue
+			}
+			class := glob[i+1 : i+1+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			sb.WriteString("[" + strings.ReplaceAll(class, `\`, `\\`) + "]")
+			i += end + 1
+		default:
+			sb.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	return sb.String()
+}
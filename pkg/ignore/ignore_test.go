@@ -0,0 +1,187 @@
+package ignore
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestGlobToRegexp(t *testing.T) {
+	tests := []struct {
+		glob  string
+		match []string
+		miss  []string
+	}{
+		{"*.go", []string{"main.go", ".go"}, []string{"cmd/main.go", "main.gox"}},
+		{"file?.txt", []string{"file1.txt"}, []string{"file.txt", "file/.txt"}},
+		{"**/build", []string{"build", "a/build", "a/b/build"}, []string{"abuild", "build/x"}},
+		{"logs/**", []string{"logs/a", "logs/a/b.log"}, []string{"logs", "other/logs/a"}},
+		{"a/**/b", []string{"a/b", "a/x/b", "a/x/y/b"}, []string{"ab", "a/b/c"}},
+		{"**.bak", []string{"x.bak", "a/b/x.bak"}, []string{"x.bak2"}},
+		{"[abc].txt", []string{"a.txt", "c.txt"}, []string{"d.txt", "ab.txt"}},
+		{"[!abc].txt", []string{"d.txt", "z.txt"}, []string{"a.txt", "b.txt"}},
+		{"[a-c]*", []string{"apple", "cherry"}, []string{"date"}},
+		{`\*.txt`, []string{"*.txt"}, []string{"a.txt"}},
+		{`what\?`, []string{"what?"}, []string{"whats"}},
+		{"a+b(1).txt", []string{"a+b(1).txt"}, []string{"aab1.txt"}},
+		{"[unclosed", []string{"[unclosed"}, []string{"u"}},
+	}
+	for _, tt := range tests {
+		re, err := regexp.Compile("^" + globToRegexp(tt.glob) + "$")
+		if err != nil {
+			t.Errorf("globToRegexp(%q) does not compile: %v", tt.glob, err)
+			continue
+		}
+		for _, p := range tt.match {
+			if !re.MatchString(p) {
+				t.Errorf("%q should match %q (regexp %s)", tt.glob, p, re)
+			}
+		}
+		for _, p := range tt.miss {
+			if re.MatchString(p) {
+				t.Errorf("%q should not match %q (regexp %s)", tt.glob, p, re)
+			}
+		}
+	}
+}
+
+func TestIgnored(t *testing.T) {
+	tests := []struct {
+		name     string
+		patterns []string
+		path     string
+		isDir    bool
+		want     bool
+	}{
+		{"name at any depth", []string{"*.log"}, "a/b/debug.log", false, true},
+		{"no match", []string{"*.log"}, "a/b/debug.txt", false, false},
+		{"leading slash anchors", []string{"/todo.txt"}, "todo.txt", false, true},
+		{"anchored pattern skips subfolders", []string{"/todo.txt"}, "docs/todo.txt", false, false},
+		{"inner slash anchors", []string{"docs/*.md"}, "docs/a.md", false, true},
+		{"inner slash anchors deeper", []string{"docs/*.md"}, "x/docs/a.md", false, false},
+		{"dir-only matches folders", []string{"build/"}, "src/build", true, true},
+		{"dir-only skips files", []string{"build/"}, "src/build", false, false},
+		{"negation re-includes", []string{"*.log", "!keep.log"}, "keep.log", false, false},
+		{"negation leaves others", []string{"*.log", "!keep.log"}, "drop.log", false, true},
+		{"later pattern wins", []string{"!keep.log", "*.log"}, "keep.log", false, true},
+		{"escaped hash", []string{`\#notes`}, "#notes", false, true},
+		{"escaped bang", []string{`\!important`}, "!important", false, true},
+		{"comment", []string{"# *.go"}, "main.go", false, false},
+		{"blank and trailing space", []string{"", "  ", "*.tmp   "}, "x.tmp", false, true},
+	}
+	for _, tt := range tests {
+		m := &Matcher{}
+		for _, p := range tt.patterns {
+			if err := m.Add(p, ""); err != nil {
+				t.Fatalf("%s: Add(%q): %v", tt.name, p, err)
+			}
+		}
+		if got := m.Ignored(tt.path, tt.isDir); got != tt.want {
+			t.Errorf("%s: Ignored(%q, %v) = %v, want %v", tt.name, tt.path, tt.isDir, got, tt.want)
+		}
+	}
+
+	var nilMatcher *Matcher
+	if nilMatcher.Ignored("anything", false) {
+		t.Error("a nil Matcher ignored a path")
+	}
+}
+
+func TestNew(t *testing.T) {
+	m, err := New([]string{"*.go", "docs/**"}, []string{"vendor/", "*_test.go"})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	tests := []struct {
+		path  string
+		isDir bool
+		want  bool
+	}{
+		{"main.go", false, false},
+		{"pkg/a/a.go", false, false},
+		{"docs/guide/intro.md", false, false},
+		{"README.md", false, true},
+		{"pkg/a/a_test.go", false, true},
+		{"vendor", true, true},
+		// Folders are walked so the files inside can match an include
+		{"pkg", true, false},
+		{"assets", true, false},
+	}
+	for _, tt := range tests {
+		if got := m.Ignored(tt.path, tt.isDir); got != tt.want {
+			t.Errorf("Ignored(%q, %v) = %v, want %v", tt.path, tt.isDir, got, tt.want)
+		}
+	}
+
+	// --exclude takes precedence over a "!" in an ignore file
+	if err := m.Add("!*_test.go", ""); err != nil {
+		t.Fatal(err)
+	}
+	if !m.Ignored("pkg/a/a_test.go", false) {
+		t.Error("an ignore file re-included a file excluded on the command line")
+	}
+}
+
+func TestLoad(t *testing.T) {
+	root := t.TempDir()
+	write := func(rel, content string) {
+		p := filepath.Join(root, filepath.FromSlash(rel))
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write(FileName, "*.tmp\n# comment\n/secret.txt\n")
+	write("web/"+FileName, "dist/\n!keep.tmp\n/local.json\n")
+
+	top := &Matcher{}
+	if err := top.Load(root, ""); err != nil {
+		t.Fatalf("Load root: %v", err)
+	}
+	web := top.Clone()
+	if err := web.Load(filepath.Join(root, "web"), "web"); err != nil {
+		t.Fatalf("Load web: %v", err)
+	}
+	// A folder without an ignore file is fine
+	if err := top.Clone().Load(filepath.Join(root, "api"), "api"); err != nil {
+		t.Errorf("Load of a folder without %s: %v", FileName, err)
+	}
+
+	tests := []struct {
+		m     *Matcher
+		name  string
+		path  string
+		isDir bool
+		want  bool
+	}{
+		{top, "root", "a.tmp", false, true},
+		{top, "root", "secret.txt", false, true},
+		{top, "root", "web/secret.txt", false, false},
+		{web, "web", "web/a.tmp", false, true},
+		{web, "web", "web/keep.tmp", false, false},
+		{web, "web", "web/dist", true, true},
+		{web, "web", "web/local.json", false, true},
+		{web, "web", "web/src/local.json", false, false},
+		// Patterns from web/ do not leak into the parent matcher or other folders
+		{top, "root", "keep.tmp", false, true},
+		{top, "root", "web/dist", true, false},
+		{web, "web", "api/dist", true, false},
+	}
+	for _, tt := range tests {
+		if got := tt.m.Ignored(tt.path, tt.isDir); got != tt.want {
+			t.Errorf("%s matcher: Ignored(%q, %v) = %v, want %v", tt.name, tt.path, tt.isDir, got, tt.want)
+		}
+	}
+}
+
+func TestReadInvalidPattern(t *testing.T) {
+	m := &Matcher{}
+	err := m.Read(strings.NewReader("ok.txt\n[z-a]\n"), "docs")
+	if err == nil || !strings.Contains(err.Error(), "docs/"+FileName) {
+		t.Errorf("Read of an invalid pattern returned %v, want an error naming docs/%s", err, FileName)
+	}
+}
@@ -0,0 +1,151 @@
+// Package mimetype detects the MIME type of local files before they are
+// uploaded, so Drive stores them with the right type instead of guessing
+// from the bytes it receives.
+package mimetype
+
+import (
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// sniffLen is how many leading bytes are read for content sniffing.
+const sniffLen = 512
+
+// fallback is used when neither the extension nor the content identifies a file.
+const fallback = "application/octet-stream"
+
+// extensions maps file extensions to MIME types. It takes precedence over
+// the system's tables, which vary between platforms and often lack office
+// and source code formats.
+var extensions = map[string]string{
+	".txt":  "text/plain",
+	".md":   "text/markdown",
+	".csv":  "text/csv",
+	".tsv":  "text/tab-separated-values",
+	".html": "text/html",
+	".htm":  "text/html",
+	".css":  "text/css",
+	".js":   "text/javascript",
+	".json": "application/json",
+	".xml":  "application/xml",
+	".yaml": "application/yaml",
+	".yml":  "application/yaml",
+	".go":   "text/x-go",
+	".py":   "text/x-python",
+	".sh":   "application/x-sh",
+	".sql":  "application/sql",
+	".pdf":  "application/pdf",
+	".rtf":  "application/rtf",
+	".doc":  "application/msword",
+	".docx": "application/vnd.openxmlformats-officedocument.wordprocessingml.document",
+	".xls":  "application/vnd.ms-excel",
+	".xlsx": "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+	".ppt":  "application/vnd.ms-powerpoint",
+	".pptx": "application/vnd.openxmlformats-officedocument.presentationml.presentation",
+	".odt":  "application/vnd.oasis.opendocument.text",
+	".ods":  "application/vnd.oasis.opendocument.spreadsheet",
+	".odp":  "application/vnd.oasis.opendocument.presentation",
+	".epub": "application/epub+zip",
+	".jpg":  "image/jpeg",
+	".jpeg": "image/jpeg",
+	".png":  "image/png",
+	".gif":  "image/gif",
+	".bmp":  "image/bmp",
+	".webp": "image/webp",
+	".svg":  "image/svg+xml",
+	".tif":  "image/tiff",
+	".tiff": "image/tiff",
+	".heic": "image/heic",
+	".mp3":  "audio/mpeg",
+	".wav":  "audio/wav",
+	".flac": "audio/flac",
+	".mp4":  "video/mp4",
+	".mov":  "video/quicktime",
+	".webm": "video/webm",
+	".mkv":  "video/x-matroska",
+	".zip":  "application/zip",
+	".gz":   "application/gzip",
+	".tgz":  "application/gzip",
+	".tar":  "application/x-tar",
+	".bz2":  "application/x-bzip2",
+	".xz":   "application/x-xz",
+	".7z":   "application/x-7z-compressed",
+}
+
+// ForExtension returns the MIME type for a file extension such as ".pdf",
+// or "" if it is unknown. Mappings in the config file's DRIVEBOX_MIME_TYPES,
+// written as "ext=type,ext=type", override the built-in ones.
+func ForExtension(ext string) string {
+	ext = strings.ToLower(ext)
+	if ext == "" {
+		return ""
+	}
+	if t, ok := configured()[ext]; ok {
+		return t
+	}
+	if t, ok := extensions[ext]; ok {
+		return t
+	}
+	if t := mime.TypeByExtension(ext); t != "" {
+		return stripParams(t)
+	}
+	return ""
+}
+
+// Detect returns the MIME type of the file named name whose content starts
+// with head. The extension is consulted first; content sniffing is used for
+// files without a known extension.
+func Detect(name string, head []byte) string {
+	if t := ForExtension(filepath.Ext(name)); t != "" {
+		return t
+	}
+	if len(head) == 0 {
+		return fallback
+	}
+	return stripParams(http.DetectContentType(head))
+}
+
+// DetectFile returns the MIME type of the file at path, reading its first
+// bytes if the extension does not identify it.
+func DetectFile(path string) (string, error) {
+	if t := ForExtension(filepath.Ext(path)); t != "" {
+		return t, nil
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	head := make([]byte, sniffLen)
+	n, err := io.ReadFull(f, head)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return "", err
+	}
+	return Detect(path, head[:n]), nil
+}
+
+// configured parses DRIVEBOX_MIME_TYPES. Extensions may be given with or
+// without the leading dot.
+func configured() map[string]string {
+	types := map[string]string{}
+	for _, entry := range strings.Split(os.Getenv("DRIVEBOX_MIME_TYPES"), ",") {
+		ext, t, ok := strings.Cut(entry, "=")
+		ext, t = strings.ToLower(strings.TrimSpace(ext)), strings.TrimSpace(t)
+		if !ok || ext == "" || t == "" {
+			continue
+		}
+		if !strings.HasPrefix(ext, ".") {
+			ext = "." + ext
+		}
+		types[ext] = t
+	}
+	return types
+}
+
+func stripParams(t string) string {
+	return strings.TrimSpace(strings.SplitN(t, ";", 2)[0])
+}
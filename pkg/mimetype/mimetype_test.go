@@ -0,0 +1,90 @@
+package mimetype
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestForExtension(t *testing.T) {
+	t.Setenv("DRIVEBOX_MIME_TYPES", "md=text/x-markdown, .LOG = text/x-log")
+	tests := []struct {
+		ext  string
+		want string
+	}{
+		{"", ""},
+		{".pdf", "application/pdf"},
+		{".PDF", "application/pdf"},
+		{".docx", "application/vnd.openxmlformats-officedocument.wordprocessingml.document"},
+		// The config file overrides the built-in table
+		{".md", "text/x-markdown"},
+		{".log", "text/x-log"},
+		{".no-such-extension", ""},
+	}
+	for _, tt := range tests {
+		if got := ForExtension(tt.ext); got != tt.want {
+			t.Errorf("ForExtension(%q) = %q, want %q", tt.ext, got, tt.want)
+		}
+	}
+}
+
+func TestConfigured(t *testing.T) {
+	t.Setenv("DRIVEBOX_MIME_TYPES", "heic=image/heif,,.Foo=application/x-foo,broken,=text/plain,bar=")
+	got := configured()
+	want := map[string]string{".heic": "image/heif", ".foo": "application/x-foo"}
+	if len(got) != len(want) {
+		t.Fatalf("configured() = %v, want %v", got, want)
+	}
+	for ext, typ := range want {
+		if got[ext] != typ {
+			t.Errorf("configured()[%q] = %q, want %q", ext, got[ext], typ)
+		}
+	}
+
+	t.Setenv("DRIVEBOX_MIME_TYPES", "")
+	if got := configured(); len(got) != 0 {
+		t.Errorf("an unset variable gave %v", got)
+	}
+}
+
+func TestDetect(t *testing.T) {
+	t.Setenv("DRIVEBOX_MIME_TYPES", "")
+	tests := []struct {
+		name string
+		head string
+		want string
+	}{
+		// The extension wins over the content
+		{"notes.md", "%PDF-1.7", "text/markdown"},
+		{"README", "plain words", "text/plain"},
+		{"scan", "%PDF-1.7", "application/pdf"},
+		{"image", "\x89PNG\r\n\x1a\n", "image/png"},
+		{"blob", "\x00\x01\x02\x03", "application/octet-stream"},
+		{"empty", "", "application/octet-stream"},
+	}
+	for _, tt := range tests {
+		if got := Detect(tt.name, []byte(tt.head)); got != tt.want {
+			t.Errorf("Detect(%q, %q) = %q, want %q", tt.name, tt.head, got, tt.want)
+		}
+	}
+}
+
+func TestDetectFile(t *testing.T) {
+	t.Setenv("DRIVEBOX_MIME_TYPES", "")
+	dir := t.TempDir()
+	path := filepath.Join(dir, "Makefile")
+	if err := os.WriteFile(path, []byte("all:\n\tgo build ./...\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := DetectFile(path); err != nil || got != "text/plain" {
+		t.Errorf("DetectFile(%q) = %q, %v, want text/plain", path, got, err)
+	}
+
+	// A known extension is not opened at all
+	if got, err := DetectFile(filepath.Join(dir, "missing.pdf")); err != nil || got != "application/pdf" {
+		t.Errorf("DetectFile of a missing .pdf = %q, %v, want application/pdf", got, err)
+	}
+	if _, err := DetectFile(filepath.Join(dir, "missing")); err == nil {
+		t.Error("DetectFile of a missing file without an extension succeeded")
+	}
+}
@@ -0,0 +1,10 @@
+package picker
+
+import "strings"
+
+// Confirm asks a yes/no question and reports whether the user answered yes.
+// Anything other than "y" or "yes", including end of input, counts as no.
+func Confirm(question string) bool {
+	answer := strings.ToLower(Prompt(question + " [y/N]: "))
+	return answer == "y" || answer == "yes"
+}
@@ -0,0 +1,61 @@
+package picker
+
+import (
+	"context"
+
+	"github.com/zohaib-a-ahmed/drivebox/pkg/drive"
+	"github.com/zohaib-a-ahmed/drivebox/pkg/format"
+	"github.com/zohaib-a-ahmed/drivebox/pkg/progress"
+)
+
+// FileFields are the fields Files needs to build its previews.
+var FileFields = []string{"id", "name", "mimeType", "parents", "size", "modifiedTime", "owners(displayName, emailAddress)"}
+
+// Files lets the user choose among Drive items, listed by path with a
+// preview of their size, modification time and owner.
+func Files(ctx context.Context, c drive.Client, files []*drive.File, opts Options) ([]*drive.File, error) {
+	paths := drive.NewPathFinder(c)
+	paths.Remember(files...)
+	items := make([]Item, len(files))
+	for i, f := range files {
+		p, err := paths.Path(ctx, f)
+		if err != nil {
+			return nil, err
+		}
+		items[i] = Item{Label: p, Details: details(f, p)}
+	}
+
+	chosen, err := Pick(items, opts)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]*drive.File, len(chosen))
+	for i, idx := range chosen {
+		out[i] = files[idx]
+	}
+	return out, nil
+}
+
+func details(f *drive.File, path string) []string {
+	size := progress.FormatBytes(f.Size)
+	switch {
+	case drive.IsFolder(f):
+		size = "folder"
+	case drive.IsWorkspace(f):
+		size = "Google Workspace document"
+	}
+	modified := format.Time(f.ModifiedTime, format.Minute)
+	owner := "unknown"
+	if len(f.Owners) > 0 {
+		owner = f.Owners[0].DisplayName
+		if f.Owners[0].EmailAddress != "" {
+			owner += " <" + f.Owners[0].EmailAddress + ">"
+		}
+	}
+	return []string{
+		"Path:     " + path,
+		"Size:     " + size,
+		"Modified: " + modified,
+		"Owner:    " + owner,
+	}
+}
@@ -0,0 +1,72 @@
+package picker
+
+import (
+	"sort"
+	"unicode"
+)
+
+// match is an item that satisfied the filter, with its relevance score.
+type match struct {
+	index int
+	score int
+}
+
+// filter returns the items matching pattern, best matches first. An empty
+// pattern keeps every item in its original order.
+func filter(items []Item, pattern string) []match {
+	var out []match
+	for i, item := range items {
+		if score, ok := fuzzyScore(item.Label, pattern); ok {
+			out = append(out, match{index: i, score: score})
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].score > out[j].score })
+	return out
+}
+
+// fuzzyScore reports whether the runes of pattern appear in order in text,
+// ignoring case, and scores the match. Consecutive runes and runes at the
+// start of a word score higher, and gaps between matched runes cost points,
+// so "qrep" ranks "q1 report.pdf" above "quarterly expenses report".
+func fuzzyScore(text, pattern string) (int, bool) {
+	if pattern == "" {
+		return 0, true
+	}
+	// Lowercase the same rune slice that orig holds, so t and orig line up
+	// index for index for the word-boundary check
+	orig := []rune(text)
+	t := lowerRunes(orig)
+	p := lowerRunes([]rune(pattern))
+
+	score, pi, last := 0, 0, -1
+	for ti := 0; ti < len(t) && pi < len(p); ti++ {
+		if t[ti] != p[pi] {
+			continue
+		}
+		score += 10
+		switch {
+		case last == ti-1:
+			score += 15
+		case last >= 0:
+			score -= ti - last - 1
+		}
+		if ti == 0 || !unicode.IsLetter(orig[ti-1]) && !unicode.IsDigit(orig[ti-1]) {
+			score += 10
+		}
+		last = ti
+		pi++
+	}
+	if pi < len(p) {
+		return 0, false
+	}
+	// Prefer shorter labels when everything else is equal.
+	return score - len(t)/10, true
+}
+
+func lowerRunes(runes []rune) []rune {
+	out := make([]rune, len(runes))
+	for i, r := range runes {
+		out[i] = unicode.ToLower(r)
+	}
+	return out
+}
@@ -0,0 +1,40 @@
+package picker
+
+import "testing"
+
+func TestFuzzyScore(t *testing.T) {
+	tests := []struct {
+		text, pattern string
+		ok            bool
+	}{
+		{"q1 report.pdf", "qrep", true},
+		{"Q1 Report.pdf", "qrep", true},
+		{"report.pdf", "xyz", false},
+		{"anything", "", true},
+		// Non-ASCII case mappings must keep the text and its lowercase aligned
+		{"İİx", "x", true},
+		{"İstanbul", "ist", true},
+		{"ÄÖÜ-notes", "äöün", true},
+	}
+	for _, tt := range tests {
+		if _, ok := fuzzyScore(tt.text, tt.pattern); ok != tt.ok {
+			t.Errorf("fuzzyScore(%q, %q) matched = %v, want %v", tt.text, tt.pattern, ok, tt.ok)
+		}
+	}
+}
+
+func TestFuzzyScoreRanking(t *testing.T) {
+	better, _ := fuzzyScore("q1 report.pdf", "qrep")
+	worse, _ := fuzzyScore("quarterly expenses report", "qrep")
+	if better <= worse {
+		t.Errorf("score of a word-start match %d should beat a scattered match %d", better, worse)
+	}
+
+	// The word-boundary bonus must look at the rune before the match, even
+	// after runes outside ASCII
+	boundary, _ := fuzzyScore("İ x", "x")
+	inside, _ := fuzzyScore("İax", "x")
+	if boundary <= inside {
+		t.Errorf("score after a space %d should beat a score inside a word %d", boundary, inside)
+	}
+}
@@ -0,0 +1,318 @@
+// Package picker lets users choose from a list of items in the terminal.
+// On a terminal it shows an interactive list with incremental fuzzy
+// filtering, arrow-key navigation, multi-select and a metadata preview.
+// Otherwise it falls back to a numbered list read line by line.
+package picker
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/term"
+)
+
+// ErrCancelled is returned when the user quits without choosing anything.
+var ErrCancelled = errors.New("selection cancelled")
+
+const (
+	// visibleRows is how many matches are listed at once in interactive mode.
+	visibleRows = 10
+	// maxLines is how many matches the line-based fallback prints.
+	maxLines = 20
+)
+
+// Item is a choice shown by the picker.
+type Item struct {
+	// Label is shown in the list and matched against the filter.
+	Label string
+	// Details are preview lines shown for the highlighted item.
+	Details []string
+}
+
+// Options configures a Pick call.
+type Options struct {
+	// Prompt is shown before the filter text.
+	Prompt string
+	// Multi allows choosing several items.
+	Multi bool
+	// Query is the initial filter text.
+	Query string
+}
+
+// Pick asks the user to choose from items and returns the indexes chosen,
+// in list order. It returns ErrCancelled if the user quits.
+func Pick(items []Item, opts Options) ([]int, error) {
+	if len(items) == 0 {
+		return nil, errors.New("nothing to choose from")
+	}
+	if opts.Prompt == "" {
+		opts.Prompt = "Filter"
+	}
+	if term.IsTerminal(int(os.Stdin.Fd())) && term.IsTerminal(int(os.Stdout.Fd())) {
+		return pickInteractive(items, opts)
+	}
+	return pickLines(items, opts, os.Stdin, os.Stdout)
+}
+
+// state is the interactive picker's view of the list.
+type state struct {
+	items    []Item
+	opts     Options
+	query    []rune
+	matches  []match
+	cursor   int
+	offset   int
+	selected map[int]bool
+}
+
+func pickInteractive(items []Item, opts Options) ([]int, error) {
+	fd := int(os.Stdin.Fd())
+	old, err := term.MakeRaw(fd)
+	if err != nil {
+		return pickLines(items, opts, os.Stdin, os.Stdout)
+	}
+	defer term.Restore(fd, old)
+
+	s := &state{items: items, opts: opts, query: []rune(opts.Query), selected: make(map[int]bool)}
+	s.refilter()
+	s.draw()
+
+	in := bufio.NewReader(os.Stdin)
+	for {
+		r, _, err := in.ReadRune()
+		if err != nil {
+			s.clear()
+			return nil, err
+		}
+		switch r {
+		case 3, 4: // Ctrl-C, Ctrl-D
+			s.clear()
+			return nil, ErrCancelled
+		case 27: // Escape, or the start of an arrow key sequence
+			if in.Buffered() == 0 {
+				s.clear()
+				return nil, ErrCancelled
+			}
+			seq, _ := in.ReadByte()
+			key, _ := in.ReadByte()
+			if seq == '[' || seq == 'O' {
+				switch key {
+				case 'A':
+					s.move(-1)
+				case 'B':
+					s.move(1)
+				case '5', '6': // Page Up/Down end with '~'
+					in.ReadByte()
+					if key == '5' {
+						s.move(-visibleRows)
+					} else {
+						s.move(visibleRows)
+					}
+				}
+			}
+		case 16: // Ctrl-P
+			s.move(-1)
+		case 14: // Ctrl-N
+			s.move(1)
+		case '\t':
+			if s.opts.Multi && len(s.matches) > 0 {
+				idx := s.matches[s.cursor].index
+				s.selected[idx] = !s.selected[idx]
+				s.move(1)
+			}
+		case '\r', '\n':
+			if len(s.matches) == 0 {
+				continue
+			}
+			s.clear()
+			return s.result(), nil
+		case 127, 8: // Backspace
+			if len(s.query) > 0 {
+				s.query = s.query[:len(s.query)-1]
+				s.refilter()
+			}
+		case 21: // Ctrl-U
+			s.query = nil
+			s.refilter()
+		default:
+			if r >= ' ' && r != utf8.RuneError {
+				s.query = append(s.query, r)
+				s.refilter()
+			}
+		}
+		s.draw()
+	}
+}
+
+// result returns the selected indexes, or the highlighted one if none are selected.
+func (s *state) result() []int {
+	var out []int
+	for i := range s.items {
+		if s.selected[i] {
+			out = append(out, i)
+		}
+	}
+	if len(out) == 0 {
+		out = []int{s.matches[s.cursor].index}
+	}
+	return out
+}
+
+func (s *state) refilter() {
+	s.matches = filter(s.items, string(s.query))
+	s.cursor, s.offset = 0, 0
+}
+
+func (s *state) move(delta int) {
+	if len(s.matches) == 0 {
+		return
+	}
+	s.cursor += delta
+	if s.cursor < 0 {
+		s.cursor = 0
+	}
+	if s.cursor >= len(s.matches) {
+		s.cursor = len(s.matches) - 1
+	}
+	if s.cursor < s.offset {
+		s.offset = s.cursor
+	}
+	if s.cursor >= s.offset+visibleRows {
+		s.offset = s.cursor - visibleRows + 1
+	}
+}
+
+// draw repaints the picker below the cursor position it started at.
+func (s *state) draw() {
+	width, _, err := term.GetSize(int(os.Stdout.Fd()))
+	if err != nil || width <= 0 {
+		width = 80
+	}
+
+	var lines []string
+	hint := "↑/↓ move, Enter choose, Esc quit"
+	if s.opts.Multi {
+		hint = "↑/↓ move, Tab select, Enter choose, Esc quit"
+	}
+	lines = append(lines, fmt.Sprintf("%d/%d  %s", len(s.matches), len(s.items), hint))
+	for i := s.offset; i < len(s.matches) && i < s.offset+visibleRows; i++ {
+		idx := s.matches[i].index
+		marker, check := "  ", " "
+		if i == s.cursor {
+			marker = "> "
+		}
+		if s.selected[idx] {
+			check = "*"
+		}
+		line := marker + check + " " + s.items[idx].Label
+		if i == s.cursor {
+			line = "\033[7m" + truncate(line, width) + "\033[0m"
+		}
+		lines = append(lines, line)
+	}
+	if len(s.matches) > 0 {
+		lines = append(lines, strings.Repeat("─", min(width, 40)))
+		for _, d := range s.items[s.matches[s.cursor].index].Details {
+			lines = append(lines, "  "+d)
+		}
+	}
+
+	// The cursor rests on the prompt line; repaint everything from there down.
+	var sb strings.Builder
+	sb.WriteString("\r\033[J")
+	for _, l := range lines {
+		sb.WriteString("\r\n" + truncate(l, width))
+	}
+	// Park the cursor at the end of the prompt line.
+	fmt.Fprintf(&sb, "\033[%dA\r%s: %s", len(lines), s.opts.Prompt, string(s.query))
+	sb.WriteString("\033[K")
+	os.Stdout.WriteString(sb.String())
+}
+
+// clear erases everything the picker drew.
+func (s *state) clear() {
+	os.Stdout.WriteString("\r\033[J")
+}
+
+// truncate shortens s to width runes; escape sequences are not counted since
+// they are only added around already-truncated text.
+func truncate(s string, width int) string {
+	if utf8.RuneCountInString(s) <= width {
+		return s
+	}
+	r := []rune(s)
+	return string(r[:width-1]) + "…"
+}
+
+// pickLines is the non-interactive picker: it prints numbered matches and
+// reads either numbers to choose or text to filter.
+func pickLines(items []Item, opts Options, r io.Reader, w io.Writer) ([]int, error) {
+	in := bufio.NewReader(r)
+	pattern := opts.Query
+	for {
+		matches := filter(items, pattern)
+		if len(matches) == 0 {
+			fmt.Fprintln(w, "No matches. Enter different text to filter.")
+		}
+		for i, m := range matches {
+			if i == maxLines {
+				fmt.Fprintf(w, "... and %d more; enter text to narrow the list\n", len(matches)-maxLines)
+				break
+			}
+			fmt.Fprintf(w, "%d: %s\n", i+1, items[m.index].Label)
+			for _, d := range items[m.index].Details {
+				fmt.Fprintf(w, "     %s\n", d)
+			}
+		}
+
+		if opts.Multi {
+			fmt.Fprint(w, "Enter numbers to choose (e.g. 1,3), text to filter, or 'quit' to exit: ")
+		} else {
+			fmt.Fprint(w, "Enter a number to choose, text to filter, or 'quit' to exit: ")
+		}
+		line, err := in.ReadString('\n')
+		line = strings.TrimSpace(line)
+		if err != nil && line == "" {
+			return nil, ErrCancelled
+		}
+		if line == "quit" {
+			return nil, ErrCancelled
+		}
+
+		if chosen, ok := parseNumbers(line, min(len(matches), maxLines), opts.Multi); ok {
+			out := make([]int, len(chosen))
+			for i, n := range chosen {
+				out[i] = matches[n-1].index
+			}
+			return out, nil
+		}
+		if _, err := strconv.Atoi(strings.Split(line, ",")[0]); err == nil {
+			fmt.Fprintln(w, "Invalid selection - try again...")
+			continue
+		}
+		pattern = line
+	}
+}
+
+// parseNumbers parses a selection like "2" or "1, 3" against n matches.
+func parseNumbers(line string, n int, multi bool) ([]int, bool) {
+	parts := strings.Split(line, ",")
+	if !multi && len(parts) > 1 {
+		return nil, false
+	}
+	var out []int
+	for _, p := range parts {
+		v, err := strconv.Atoi(strings.TrimSpace(p))
+		if err != nil || v < 1 || v > n {
+			return nil, false
+		}
+		out = append(out, v)
+	}
+	return out, len(out) > 0
+}
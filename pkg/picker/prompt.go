@@ -0,0 +1,24 @@
+package picker
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Prompt prints prompt and returns the line the user types, trimmed. It
+// reads a whole line so that names containing spaces survive, and reads a
+// byte at a time so no input meant for a later prompt is buffered away.
+func Prompt(prompt string) string {
+	fmt.Print(prompt)
+	var line []byte
+	buf := make([]byte, 1)
+	for {
+		n, err := os.Stdin.Read(buf)
+		if n == 0 || err != nil || buf[0] == '\n' {
+			break
+		}
+		line = append(line, buf[0])
+	}
+	return strings.TrimSpace(string(line))
+}
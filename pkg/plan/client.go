@@ -0,0 +1,270 @@
+package plan
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+	"sync"
+
+	"github.com/zohaib-a-ahmed/drivebox/pkg/drive"
+)
+
+// plannedPrefix starts the IDs given to items that would have been created.
+const plannedPrefix = "planned-"
+
+// Client is a drive.Client that passes lookups to the wrapped client and
+// records changes in the plan instead of making them. Items it "creates" are
+// remembered under placeholder IDs, so commands can keep working with them,
+// for example by creating files inside a planned folder.
+type Client struct {
+	drive.Client
+
+	mu      sync.Mutex
+	planned map[string]*drive.File
+	next    int
+	paths   *drive.PathFinder
+}
+
+// Wrap returns a Client that plans the changes made through c.
+func Wrap(c drive.Client) *Client {
+	p := &Client{Client: c, planned: make(map[string]*drive.File)}
+	p.paths = drive.NewPathFinder(p)
+	return p
+}
+
+// Unwrap returns the client changes would have been made through.
+func (c *Client) Unwrap() drive.Client {
+	return c.Client
+}
+
+func (c *Client) List(ctx context.Context, opts drive.ListOptions) ([]*drive.File, error) {
+	// Nothing exists inside a folder that has not been created
+	if strings.Contains(opts.Query, plannedPrefix) {
+		return nil, nil
+	}
+	return c.Client.List(ctx, opts)
+}
+
+func (c *Client) Get(ctx context.Context, id string, fields ...string) (*drive.File, error) {
+	if f, ok := c.lookup(id); ok {
+		return f, nil
+	}
+	return c.Client.Get(ctx, id, fields...)
+}
+
+func (c *Client) Create(ctx context.Context, f *drive.File, media io.Reader, opts drive.CreateOptions) (*drive.File, error) {
+	nf := c.remember(f)
+	if len(nf.Parents) == 0 {
+		nf.Parents = []string{drive.Root(c.Client)}
+	}
+	p := c.path(ctx, nf)
+	if drive.IsFolder(nf) {
+		Add(Action{Op: Mkdir, Path: p})
+		return nf, nil
+	}
+
+	detail := nf.MimeType
+	if opts.ContentType != "" && opts.ContentType != nf.MimeType {
+		detail = "converted from " + opts.ContentType + " to " + nf.MimeType
+	}
+	if !isPlanned(nf.Parents[0]) {
+		existing, err := drive.Child(ctx, c.Client, nf.Parents[0], nf.Name, "id")
+		if err == nil && existing != nil {
+			detail = join(detail, "an item with this name already exists; Drive would keep both")
+		}
+	}
+	Add(Action{Op: Create, Path: p, Detail: detail})
+	return nf, nil
+}
+
+func (c *Client) Update(ctx context.Context, id string, f *drive.File, opts drive.UpdateOptions) (*drive.File, error) {
+	cur, err := c.Get(ctx, id, "id", "name", "mimeType", "parents")
+	if err != nil {
+		return nil, err
+	}
+	p := c.path(ctx, cur)
+	updated := *cur
+
+	switch {
+	case len(opts.AddParents) > 0:
+		name := cur.Name
+		if f.Name != "" {
+			name = f.Name
+		}
+		Add(Action{Op: Move, Path: p, Target: c.path(ctx, &drive.File{Name: name, Parents: opts.AddParents})})
+		updated.Name = name
+		updated.Parents = moveParents(cur.Parents, opts.AddParents, opts.RemoveParents)
+	case opts.Media != nil:
+		Add(Action{Op: Overwrite, Path: p})
+	case f.Trashed:
+		Add(Action{Op: Trash, Path: p})
+		updated.Trashed = true
+	case contains(f.ForceSendFields, "Trashed"):
+		Add(Action{Op: Restore, Path: p})
+		updated.Trashed = false
+	case f.Name != "" && f.Name != cur.Name:
+		Add(Action{Op: Rename, Path: p, Target: path.Join(path.Dir(p), f.Name)})
+		updated.Name = f.Name
+	default:
+		Add(Action{Op: Update, Path: p})
+	}
+	c.store(&updated)
+	return &updated, nil
+}
+
+func (c *Client) Move(ctx context.Context, id string, addParents, removeParents []string) (*drive.File, error) {
+	cur, err := c.Get(ctx, id, "id", "name", "mimeType", "parents")
+	if err != nil {
+		return nil, err
+	}
+	moved := *cur
+	moved.Parents = moveParents(cur.Parents, addParents, removeParents)
+	Add(Action{Op: Move, Path: c.path(ctx, cur), Target: c.path(ctx, &drive.File{Name: cur.Name, Parents: addParents})})
+	c.store(&moved)
+	return &moved, nil
+}
+
+func (c *Client) Copy(ctx context.Context, id string, f *drive.File, fields ...string) (*drive.File, error) {
+	src, err := c.Get(ctx, id, "id", "name", "mimeType", "parents")
+	if err != nil {
+		return nil, err
+	}
+	cp := *f
+	if cp.Name == "" {
+		cp.Name = src.Name
+	}
+	if len(cp.Parents) == 0 {
+		cp.Parents = src.Parents
+	}
+	if cp.MimeType == "" {
+		cp.MimeType = src.MimeType
+	}
+	nf := c.remember(&cp)
+	Add(Action{Op: Copy, Path: c.path(ctx, src), Target: c.path(ctx, nf)})
+	return nf, nil
+}
+
+func (c *Client) Delete(ctx context.Context, id string) error {
+	cur, err := c.Get(ctx, id, "id", "name", "mimeType", "parents")
+	if err != nil {
+		return err
+	}
+	Add(Action{Op: Delete, Path: c.path(ctx, cur)})
+	return nil
+}
+
+func (c *Client) EmptyTrash(ctx context.Context, driveID string) error {
+	Add(Action{Op: EmptyTrash, Detail: "every item in the trash would be deleted"})
+	return nil
+}
+
+func (c *Client) Permissions(ctx context.Context, id string) ([]*drive.Permission, error) {
+	if isPlanned(id) {
+		return nil, nil
+	}
+	return c.Client.Permissions(ctx, id)
+}
+
+func (c *Client) Share(ctx context.Context, id string, p *drive.Permission, opts drive.ShareOptions) (*drive.Permission, error) {
+	cur, err := c.Get(ctx, id, "id", "name", "mimeType", "parents")
+	if err != nil {
+		return nil, err
+	}
+	Add(Action{Op: Share, Path: c.path(ctx, cur), Detail: fmt.Sprintf("%s as %s", drive.Grantee(p), p.Role)})
+	shared := *p
+	shared.Id = plannedPrefix + "permission"
+	return &shared, nil
+}
+
+func (c *Client) Unshare(ctx context.Context, id, permissionID string) error {
+	cur, err := c.Get(ctx, id, "id", "name", "mimeType", "parents")
+	if err != nil {
+		return err
+	}
+	detail := permissionID
+	if perms, err := c.Permissions(ctx, id); err == nil {
+		for _, p := range perms {
+			if p.Id == permissionID {
+				detail = drive.Grantee(p)
+			}
+		}
+	}
+	Add(Action{Op: Unshare, Path: c.path(ctx, cur), Detail: detail})
+	return nil
+}
+
+// remember stores a copy of f under a new placeholder ID and returns it.
+func (c *Client) remember(f *drive.File) *drive.File {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.next++
+	nf := *f
+	nf.Id = fmt.Sprintf("%s%d", plannedPrefix, c.next)
+	nf.Parents = append([]string(nil), f.Parents...)
+	c.planned[nf.Id] = &nf
+	c.paths.Remember(&nf)
+	return &nf
+}
+
+// store records the planned state of an item after a change, so later
+// lookups see it renamed, moved or trashed.
+func (c *Client) store(f *drive.File) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	stored := *f
+	c.planned[f.Id] = &stored
+}
+
+func (c *Client) lookup(id string) (*drive.File, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	f, ok := c.planned[id]
+	if !ok {
+		return nil, false
+	}
+	cp := *f
+	return &cp, true
+}
+
+// path returns the Drive path of f for the plan, or its name if the path
+// cannot be worked out.
+func (c *Client) path(ctx context.Context, f *drive.File) string {
+	p, err := c.paths.Path(ctx, f)
+	if err != nil || p == "" {
+		return f.Name
+	}
+	return p
+}
+
+// moveParents returns parents with removeParents taken out and addParents added.
+func moveParents(parents, addParents, removeParents []string) []string {
+	var out []string
+	for _, parent := range parents {
+		if !contains(removeParents, parent) {
+			out = append(out, parent)
+		}
+	}
+	return append(out, addParents...)
+}
+
+func isPlanned(id string) bool {
+	return strings.HasPrefix(id, plannedPrefix)
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+func join(a, b string) string {
+	if a == "" {
+		return b
+	}
+	return a + "; " + b
+}
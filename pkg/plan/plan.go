@@ -0,0 +1,128 @@
+// Package plan implements dry runs. With the global --dry-run flag, lookups
+// still reach Drive but every change is recorded as an Action instead of
+// being made, and the resulting plan is printed when the command finishes.
+package plan
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"text/tabwriter"
+)
+
+var (
+	// DryRun replaces changes with a printed plan (bound to the global --dry-run flag).
+	DryRun bool
+	// Format is how the plan is printed, "text" or "json" (bound to the global --plan-format flag).
+	Format = "text"
+)
+
+// Operations recorded in a plan.
+const (
+	Create     = "create"
+	Mkdir      = "mkdir"
+	Update     = "update"
+	Rename     = "rename"
+	Overwrite  = "overwrite"
+	Move       = "move"
+	Copy       = "copy"
+	Trash      = "trash"
+	Restore    = "restore"
+	Delete     = "delete"
+	EmptyTrash = "empty-trash"
+	Share      = "share"
+	Unshare    = "unshare"
+	Download   = "download"
+)
+
+// Action is one change a command would have made.
+type Action struct {
+	// Op is what would be done, one of the operations above.
+	Op string `json:"action"`
+	// Path is the Drive path of the item acted on.
+	Path string `json:"path,omitempty"`
+	// Target is where the item would end up: a Drive path for moves, copies
+	// and renames, or a local path for downloads.
+	Target string `json:"target,omitempty"`
+	// Detail adds context, such as a MIME type, a grantee or a conflict.
+	Detail string `json:"detail,omitempty"`
+}
+
+var (
+	mu      sync.Mutex
+	actions []Action
+	out     io.Writer = os.Stdout
+)
+
+// Add records an action.
+func Add(a Action) {
+	mu.Lock()
+	defer mu.Unlock()
+	actions = append(actions, a)
+}
+
+// Actions returns the actions recorded so far.
+func Actions() []Action {
+	mu.Lock()
+	defer mu.Unlock()
+	return append([]Action(nil), actions...)
+}
+
+// UseStderr prints the plan on standard error instead of standard output,
+// for commands that write file content or data to standard output.
+func UseStderr() {
+	mu.Lock()
+	defer mu.Unlock()
+	out = os.Stderr
+}
+
+// CheckFormat reports whether Format names a known plan format.
+func CheckFormat() error {
+	if Format != "text" && Format != "json" {
+		return fmt.Errorf("unknown plan format %q: expected text or json", Format)
+	}
+	return nil
+}
+
+// Print writes the recorded plan in Format to standard output, or to
+// standard error after UseStderr.
+func Print() error {
+	if err := CheckFormat(); err != nil {
+		return err
+	}
+	planned := Actions()
+	mu.Lock()
+	w := out
+	mu.Unlock()
+	if Format == "json" {
+		if planned == nil {
+			planned = []Action{}
+		}
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(struct {
+			DryRun  bool     `json:"dryRun"`
+			Actions []Action `json:"actions"`
+		}{true, planned})
+	}
+
+	if len(planned) == 0 {
+		fmt.Fprintln(w, "Dry run: nothing would be changed.")
+		return nil
+	}
+	fmt.Fprintf(w, "Dry run: %d planned action(s), nothing was changed.\n", len(planned))
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	for _, a := range planned {
+		line := a.Op + "\t" + a.Path
+		if a.Target != "" {
+			line += " -> " + a.Target
+		}
+		if a.Detail != "" {
+			line += "\t(" + a.Detail + ")"
+		}
+		fmt.Fprintln(tw, line)
+	}
+	return tw.Flush()
+}
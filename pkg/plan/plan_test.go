@@ -0,0 +1,241 @@
+package plan
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/zohaib-a-ahmed/drivebox/pkg/drive"
+	"github.com/zohaib-a-ahmed/drivebox/pkg/query"
+)
+
+// reset clears the recorded plan and sends the printed plan to buf.
+func reset(t *testing.T, buf *bytes.Buffer) {
+	t.Helper()
+	mu.Lock()
+	w := out
+	actions, out = nil, buf
+	mu.Unlock()
+	format := Format
+	t.Cleanup(func() {
+		mu.Lock()
+		actions, out = nil, w
+		mu.Unlock()
+		Format = format
+	})
+}
+
+// listCounter counts the List calls that reach Drive.
+type listCounter struct {
+	drive.Client
+	lists int
+}
+
+func (c *listCounter) List(ctx context.Context, opts drive.ListOptions) ([]*drive.File, error) {
+	c.lists++
+	return c.Client.List(ctx, opts)
+}
+
+func seed(t *testing.T) (*drive.Memory, *drive.File, *drive.File, *drive.File) {
+	t.Helper()
+	ctx := context.Background()
+	mem := drive.NewMemory()
+	docs, err := mem.Create(ctx, &drive.File{Name: "Docs", MimeType: drive.FolderMimeType}, nil, drive.CreateOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	archive, err := mem.Create(ctx, &drive.File{Name: "Archive", MimeType: drive.FolderMimeType}, nil, drive.CreateOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	report, err := mem.Create(ctx, &drive.File{Name: "report.pdf", Parents: []string{docs.Id}}, strings.NewReader("pdf"), drive.CreateOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return mem, docs, archive, report
+}
+
+func TestUpdate(t *testing.T) {
+	ctx := context.Background()
+	tests := []struct {
+		name   string
+		update func(f *drive.File) (*drive.File, drive.UpdateOptions)
+		want   Action
+	}{
+		{"rename", func(f *drive.File) (*drive.File, drive.UpdateOptions) {
+			return &drive.File{Name: "final.pdf"}, drive.UpdateOptions{}
+		}, Action{Op: Rename, Path: "/Docs/report.pdf", Target: "/Docs/final.pdf"}},
+		{"trash", func(f *drive.File) (*drive.File, drive.UpdateOptions) {
+			return &drive.File{Trashed: true}, drive.UpdateOptions{}
+		}, Action{Op: Trash, Path: "/Docs/report.pdf"}},
+		{"restore", func(f *drive.File) (*drive.File, drive.UpdateOptions) {
+			return &drive.File{ForceSendFields: []string{"Trashed"}}, drive.UpdateOptions{}
+		}, Action{Op: Restore, Path: "/Docs/report.pdf"}},
+		{"overwrite", func(f *drive.File) (*drive.File, drive.UpdateOptions) {
+			return &drive.File{}, drive.UpdateOptions{Media: strings.NewReader("new")}
+		}, Action{Op: Overwrite, Path: "/Docs/report.pdf"}},
+		{"same name", func(f *drive.File) (*drive.File, drive.UpdateOptions) {
+			return &drive.File{Name: "report.pdf", Description: "quarterly"}, drive.UpdateOptions{}
+		}, Action{Op: Update, Path: "/Docs/report.pdf"}},
+	}
+	for _, tt := range tests {
+		var buf bytes.Buffer
+		reset(t, &buf)
+		mem, _, _, report := seed(t)
+		c := Wrap(mem)
+		f, opts := tt.update(report)
+		if _, err := c.Update(ctx, report.Id, f, opts); err != nil {
+			t.Fatalf("%s: Update: %v", tt.name, err)
+		}
+		if got := Actions(); len(got) != 1 || got[0] != tt.want {
+			t.Errorf("%s: planned %+v, want %+v", tt.name, got, tt.want)
+		}
+
+		// Nothing reached Drive
+		stored, err := mem.Get(ctx, report.Id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if stored.Name != "report.pdf" || stored.Trashed {
+			t.Errorf("%s: the wrapped client was changed: %+v", tt.name, stored)
+		}
+	}
+}
+
+func TestUpdateMoveAndRename(t *testing.T) {
+	ctx := context.Background()
+	var buf bytes.Buffer
+	reset(t, &buf)
+	mem, docs, archive, report := seed(t)
+	c := Wrap(mem)
+
+	opts := drive.UpdateOptions{AddParents: []string{archive.Id}, RemoveParents: []string{docs.Id}}
+	if _, err := c.Update(ctx, report.Id, &drive.File{Name: "2024.pdf"}, opts); err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	want := Action{Op: Move, Path: "/Docs/report.pdf", Target: "/Archive/2024.pdf"}
+	if got := Actions(); len(got) != 1 || got[0] != want {
+		t.Errorf("planned %+v, want %+v", got, want)
+	}
+
+	// Later lookups see the planned state
+	moved, err := c.Get(ctx, report.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if moved.Name != "2024.pdf" || len(moved.Parents) != 1 || moved.Parents[0] != archive.Id {
+		t.Errorf("planned file is %q in %v, want 2024.pdf in %s", moved.Name, moved.Parents, archive.Id)
+	}
+}
+
+func TestPlannedFolder(t *testing.T) {
+	ctx := context.Background()
+	var buf bytes.Buffer
+	reset(t, &buf)
+	mem, _, _, _ := seed(t)
+	counter := &listCounter{Client: mem}
+	c := Wrap(counter)
+
+	folder, err := c.Create(ctx, &drive.File{Name: "New", MimeType: drive.FolderMimeType}, nil, drive.CreateOptions{})
+	if err != nil {
+		t.Fatalf("Create folder: %v", err)
+	}
+	if !isPlanned(folder.Id) {
+		t.Fatalf("planned folder has ID %q", folder.Id)
+	}
+
+	// Nothing can exist inside a folder that was never created
+	files, err := c.List(ctx, drive.ListOptions{Query: query.InParents(folder.Id).String()})
+	if err != nil || len(files) != 0 {
+		t.Errorf("List in a planned folder returned %v, %v", files, err)
+	}
+	if counter.lists != 0 {
+		t.Errorf("List in a planned folder reached Drive %d times", counter.lists)
+	}
+	if _, err := c.List(ctx, drive.ListOptions{Query: query.Trashed(false).String()}); err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if counter.lists != 1 {
+		t.Errorf("other lists should reach Drive, got %d calls", counter.lists)
+	}
+
+	if _, err := c.Create(ctx, &drive.File{Name: "notes.txt", MimeType: "text/plain", Parents: []string{folder.Id}}, strings.NewReader("x"), drive.CreateOptions{}); err != nil {
+		t.Fatalf("Create file: %v", err)
+	}
+	want := []Action{
+		{Op: Mkdir, Path: "/New"},
+		{Op: Create, Path: "/New/notes.txt", Detail: "text/plain"},
+	}
+	got := Actions()
+	if len(got) != len(want) {
+		t.Fatalf("planned %+v, want %+v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("action %d is %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestPrint(t *testing.T) {
+	var buf bytes.Buffer
+	reset(t, &buf)
+
+	Format = "text"
+	if err := Print(); err != nil {
+		t.Fatalf("Print: %v", err)
+	}
+	if buf.String() != "Dry run: nothing would be changed.\n" {
+		t.Errorf("empty text plan is %q", buf.String())
+	}
+
+	Add(Action{Op: Move, Path: "/a.txt", Target: "/Archive/a.txt"})
+	Add(Action{Op: Share, Path: "/b.txt", Detail: "user alice@example.com as reader"})
+	buf.Reset()
+	if err := Print(); err != nil {
+		t.Fatalf("Print: %v", err)
+	}
+	want := "Dry run: 2 planned action(s), nothing was changed.\n" +
+		"move   /a.txt -> /Archive/a.txt\n" +
+		"share  /b.txt  (user alice@example.com as reader)\n"
+	if buf.String() != want {
+		t.Errorf("text plan is\n%s\nwant\n%s", buf.String(), want)
+	}
+
+	Format = "json"
+	buf.Reset()
+	if err := Print(); err != nil {
+		t.Fatalf("Print: %v", err)
+	}
+	var decoded struct {
+		DryRun  bool     `json:"dryRun"`
+		Actions []Action `json:"actions"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil {
+		t.Fatalf("JSON plan does not parse: %v\n%s", err, buf.String())
+	}
+	if !decoded.DryRun || len(decoded.Actions) != 2 || decoded.Actions[0].Target != "/Archive/a.txt" {
+		t.Errorf("JSON plan is %+v", decoded)
+	}
+	if strings.Count(buf.String(), `"detail"`) != 1 {
+		t.Errorf("empty details should be left out of the JSON plan: %s", buf.String())
+	}
+
+	mu.Lock()
+	actions = nil
+	mu.Unlock()
+	buf.Reset()
+	if err := Print(); err != nil {
+		t.Fatalf("Print: %v", err)
+	}
+	if !strings.Contains(buf.String(), `"actions": []`) {
+		t.Errorf("empty JSON plan should list no actions, got %s", buf.String())
+	}
+
+	Format = "yaml"
+	if err := Print(); err == nil {
+		t.Error("Print accepted an unknown format")
+	}
+}
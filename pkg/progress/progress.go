@@ -0,0 +1,210 @@
+package progress
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/term"
+)
+
+// Quiet suppresses all progress output when set (bound to the global --quiet flag).
+var Quiet bool
+
+const (
+	// redrawInterval limits how often the terminal is repainted.
+	redrawInterval = 100 * time.Millisecond
+	// logInterval is the spacing between log lines when stdout is not a terminal.
+	logInterval = 5 * time.Second
+)
+
+var (
+	mu       sync.Mutex
+	active   []*Bar
+	drawn    int
+	lastDraw time.Time
+	out      = os.Stdout
+	isTTY    = term.IsTerminal(int(out.Fd()))
+)
+
+// UseStderr draws progress on standard error instead of standard output, for
+// commands that write file content to standard output.
+func UseStderr() {
+	mu.Lock()
+	defer mu.Unlock()
+	out = os.Stderr
+	isTTY = term.IsTerminal(int(out.Fd()))
+}
+
+// Bar tracks the progress of a single transfer. Several bars may be active at
+// once; they are rendered together as a block of lines on a terminal.
+type Bar struct {
+	name     string
+	total    int64
+	current  atomic.Int64
+	start    time.Time
+	lastLog  time.Time
+	finished bool
+}
+
+// New starts tracking a transfer of total bytes. A total of zero or less means
+// the size is unknown, in which case only the transferred bytes and rate are shown.
+func New(name string, total int64) *Bar {
+	b := &Bar{name: name, total: total, start: time.Now()}
+	b.lastLog = b.start
+	if Quiet {
+		return b
+	}
+
+	mu.Lock()
+	active = append(active, b)
+	mu.Unlock()
+	return b
+}
+
+// Set records the absolute number of bytes transferred so far.
+func (b *Bar) Set(n int64) {
+	b.current.Store(n)
+	render(false)
+}
+
+// Add records n more transferred bytes.
+func (b *Bar) Add(n int64) {
+	b.current.Add(n)
+	render(false)
+}
+
+// Reader wraps r so that every read advances the bar.
+func (b *Bar) Reader(r io.Reader) io.Reader {
+	return &reader{r: r, bar: b}
+}
+
+// Finish marks the transfer complete and renders its final state.
+func (b *Bar) Finish() {
+	mu.Lock()
+	b.finished = true
+	mu.Unlock()
+	render(true)
+}
+
+type reader struct {
+	r   io.Reader
+	bar *Bar
+}
+
+func (r *reader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	if n > 0 {
+		r.bar.Add(int64(n))
+	}
+	return n, err
+}
+
+// render repaints the active bars. Unless force is set, terminal redraws are
+// rate limited and non-terminal output only logs every logInterval.
+func render(force bool) {
+	if Quiet {
+		return
+	}
+	mu.Lock()
+	defer mu.Unlock()
+
+	now := time.Now()
+	if !isTTY {
+		remaining := active[:0]
+		for _, b := range active {
+			if b.finished || now.Sub(b.lastLog) >= logInterval {
+				log.Println(b.line(now))
+				b.lastLog = now
+			}
+			if !b.finished {
+				remaining = append(remaining, b)
+			}
+		}
+		active = remaining
+		return
+	}
+
+	if !force && now.Sub(lastDraw) < redrawInterval {
+		return
+	}
+	lastDraw = now
+
+	var sb strings.Builder
+	if drawn > 0 {
+		fmt.Fprintf(&sb, "\033[%dA", drawn)
+	}
+	// Finished bars are printed first so they scroll off as permanent lines and
+	// the block of in-flight bars stays at the bottom.
+	remaining := active[:0]
+	for _, b := range active {
+		if b.finished {
+			sb.WriteString("\r\033[2K" + b.line(now) + "\n")
+		}
+	}
+	for _, b := range active {
+		if !b.finished {
+			sb.WriteString("\r\033[2K" + b.line(now) + "\n")
+			remaining = append(remaining, b)
+		}
+	}
+	active = remaining
+	drawn = len(active)
+	fmt.Fprint(out, sb.String())
+}
+
+// line formats the bar as a single status line.
+func (b *Bar) line(now time.Time) string {
+	current := b.current.Load()
+	elapsed := now.Sub(b.start)
+	var rate float64
+	if elapsed > 0 {
+		rate = float64(current) / elapsed.Seconds()
+	}
+
+	if b.total <= 0 {
+		if b.finished {
+			return fmt.Sprintf("%s: %s done in %s (%s/s)", b.name, FormatBytes(current), formatDuration(elapsed), FormatBytes(int64(rate)))
+		}
+		return fmt.Sprintf("%s: %s at %s/s", b.name, FormatBytes(current), FormatBytes(int64(rate)))
+	}
+
+	percent := float64(current) / float64(b.total) * 100
+	if b.finished {
+		return fmt.Sprintf("%s: %s done in %s (%s/s)", b.name, FormatBytes(b.total), formatDuration(elapsed), FormatBytes(int64(rate)))
+	}
+
+	eta := "--"
+	if rate > 0 {
+		eta = formatDuration(time.Duration(float64(b.total-current) / rate * float64(time.Second)))
+	}
+	return fmt.Sprintf("%s: %5.1f%% %s / %s at %s/s, ETA %s",
+		b.name, percent, FormatBytes(current), FormatBytes(b.total), FormatBytes(int64(rate)), eta)
+}
+
+// FormatBytes renders a byte count using binary units, e.g. "1.5 MiB".
+func FormatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
+
+// formatDuration renders a duration rounded to the second, e.g. "1m5s".
+func formatDuration(d time.Duration) string {
+	if d < time.Second {
+		return "0s"
+	}
+	return d.Round(time.Second).String()
+}
@@ -0,0 +1,88 @@
+package progress
+
+import (
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFormatBytes(t *testing.T) {
+	tests := []struct {
+		n    int64
+		want string
+	}{
+		{0, "0 B"},
+		{1023, "1023 B"},
+		{1024, "1.0 KiB"},
+		{1536, "1.5 KiB"},
+		{5 << 20, "5.0 MiB"},
+		{3 << 30, "3.0 GiB"},
+		{2 << 40, "2.0 TiB"},
+	}
+	for _, tt := range tests {
+		if got := FormatBytes(tt.n); got != tt.want {
+			t.Errorf("FormatBytes(%d) = %q, want %q", tt.n, got, tt.want)
+		}
+	}
+}
+
+func TestFormatDuration(t *testing.T) {
+	tests := []struct {
+		d    time.Duration
+		want string
+	}{
+		{0, "0s"},
+		{400 * time.Millisecond, "0s"},
+		{1600 * time.Millisecond, "2s"},
+		{65 * time.Second, "1m5s"},
+	}
+	for _, tt := range tests {
+		if got := formatDuration(tt.d); got != tt.want {
+			t.Errorf("formatDuration(%s) = %q, want %q", tt.d, got, tt.want)
+		}
+	}
+}
+
+func TestLine(t *testing.T) {
+	now := time.Now()
+	bar := func(total, current int64, finished bool) *Bar {
+		b := &Bar{name: "report.pdf", total: total, start: now.Add(-2 * time.Second), finished: finished}
+		b.current.Store(current)
+		return b
+	}
+	tests := []struct {
+		name string
+		b    *Bar
+		want string
+	}{
+		{"in progress", bar(1024, 512, false), "report.pdf:  50.0% 512 B / 1.0 KiB at 256 B/s, ETA 2s"},
+		{"finished", bar(1024, 1024, true), "report.pdf: 1.0 KiB done in 2s (512 B/s)"},
+		{"unknown size", bar(0, 512, false), "report.pdf: 512 B at 256 B/s"},
+		{"unknown size finished", bar(-1, 512, true), "report.pdf: 512 B done in 2s (256 B/s)"},
+		{"nothing sent yet", bar(1024, 0, false), "report.pdf:   0.0% 0 B / 1.0 KiB at 0 B/s, ETA --"},
+	}
+	for _, tt := range tests {
+		if got := tt.b.line(now); got != tt.want {
+			t.Errorf("%s: got %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestReader(t *testing.T) {
+	Quiet = true
+	defer func() { Quiet = false }()
+
+	b := New("data", 11)
+	n, err := io.Copy(io.Discard, b.Reader(strings.NewReader("hello world")))
+	if err != nil {
+		t.Fatalf("Copy: %v", err)
+	}
+	b.Finish()
+	if n != 11 || b.current.Load() != 11 {
+		t.Errorf("copied %d bytes and the bar counted %d, want 11", n, b.current.Load())
+	}
+	if len(active) != 0 {
+		t.Errorf("quiet bars should not be drawn, got %d active", len(active))
+	}
+}
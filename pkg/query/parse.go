@@ -0,0 +1,474 @@
+package query
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"google.golang.org/api/drive/v3"
+)
+
+// Expr is a parsed Drive search query that can be evaluated against file
+// metadata. It lets fakes and caches answer Files.List queries locally.
+type Expr interface {
+	Match(f *drive.File) bool
+}
+
+// Parse parses a Drive v3 search query such as
+// "name contains 'report' and 'root' in parents and trashed = false".
+// An empty query matches every file.
+func Parse(q string) (Expr, error) {
+	toks, err := tokenize(q)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{toks: toks}
+	if len(toks) == 0 {
+		return matchAll{}, nil
+	}
+	e, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.toks) {
+		return nil, fmt.Errorf("invalid query: unexpected %q", p.toks[p.pos].text)
+	}
+	return e, nil
+}
+
+type tokenKind int
+
+const (
+	tokWord tokenKind = iota
+	tokString
+	tokOp
+	tokLParen
+	tokRParen
+	tokLBrace
+	tokRBrace
+)
+
+type token struct {
+	kind tokenKind
+	text string
+}
+
+func tokenize(q string) ([]token, error) {
+	var toks []token
+	r := []rune(q)
+	for i := 0; i < len(r); {
+		c := r[i]
+		switch {
+		case unicode.IsSpace(c):
+			i++
+		case c == '(':
+			toks = append(toks, token{tokLParen, "("})
+			i++
+		case c == ')':
+			toks = append(toks, token{tokRParen, ")"})
+			i++
+		case c == '{':
+			toks = append(toks, token{tokLBrace, "{"})
+			i++
+		case c == '}':
+			toks = append(toks, token{tokRBrace, "}"})
+			i++
+		case c == '\'':
+			var sb strings.Builder
+			i++
+			closed := false
+			for i < len(r) {
+				if r[i] == '\\' && i+1 < len(r) {
+					sb.WriteRune(r[i+1])
+					i += 2
+					continue
+				}
+				if r[i] == '\'' {
+					closed = true
+					i++
+					break
+				}
+				sb.WriteRune(r[i])
+				i++
+			}
+			if !closed {
+				return nil, fmt.Errorf("invalid query: unterminated string")
+			}
+			toks = append(toks, token{tokString, sb.String()})
+		case c == '=' || c == '!' || c == '<' || c == '>':
+			op := string(c)
+			if i+1 < len(r) && r[i+1] == '=' {
+				op += "="
+				i++
+			}
+			if op == "!" {
+				return nil, fmt.Errorf("invalid query: unexpected '!'")
+			}
+			toks = append(toks, token{tokOp, op})
+			i++
+		default:
+			start := i
+			for i < len(r) && !unicode.IsSpace(r[i]) && !strings.ContainsRune("(){}'=!<>", r[i]) {
+				i++
+			}
+			toks = append(toks, token{tokWord, string(r[start:i])})
+		}
+	}
+	return toks, nil
+}
+
+type parser struct {
+	toks []token
+	pos  int
+}
+
+func (p *parser) peek() *token {
+	if p.pos < len(p.toks) {
+		return &p.toks[p.pos]
+	}
+	return nil
+}
+
+func (p *parser) next() (token, error) {
+	if p.pos >= len(p.toks) {
+		return token{}, fmt.Errorf("invalid query: unexpected end of query")
+	}
+	t := p.toks[p.pos]
+	p.pos++
+	return t, nil
+}
+
+func (p *parser) keyword(word string) bool {
+	if t := p.peek(); t != nil && t.kind == tokWord && strings.EqualFold(t.text, word) {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *parser) parseOr() (Expr, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.keyword("or") {
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = or{left, right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseAnd() (Expr, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for p.keyword("and") {
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = and{left, right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseUnary() (Expr, error) {
+	if p.keyword("not") {
+		e, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return not{e}, nil
+	}
+	if t := p.peek(); t != nil && t.kind == tokLParen {
+		p.pos++
+		e, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if t, err := p.next(); err != nil || t.kind != tokRParen {
+			return nil, fmt.Errorf("invalid query: missing ')'")
+		}
+		return e, nil
+	}
+	return p.parseTerm()
+}
+
+// parseTerm parses "field op value", "'value' in field" and
+// "properties has { key='k' and value='v' }".
+func (p *parser) parseTerm() (Expr, error) {
+	t, err := p.next()
+	if err != nil {
+		return nil, err
+	}
+
+	if t.kind == tokString {
+		if !p.keyword("in") {
+			return nil, fmt.Errorf("invalid query: expected 'in' after %q", t.text)
+		}
+		field, err := p.next()
+		if err != nil {
+			return nil, err
+		}
+		switch field.text {
+		case "parents", "owners", "writers", "readers":
+			return in{value: t.text, field: field.text}, nil
+		}
+		return nil, fmt.Errorf("invalid query: field %q does not support 'in'", field.text)
+	}
+
+	if t.kind != tokWord {
+		return nil, fmt.Errorf("invalid query: unexpected %q", t.text)
+	}
+	field := t.text
+
+	if p.keyword("has") {
+		if field != "properties" && field != "appProperties" {
+			return nil, fmt.Errorf("invalid query: field %q does not support 'has'", field)
+		}
+		return p.parseHas(field)
+	}
+
+	opTok, err := p.next()
+	if err != nil {
+		return nil, err
+	}
+	op := opTok.text
+	if opTok.kind == tokWord {
+		op = strings.ToLower(op)
+		if op != "contains" {
+			return nil, fmt.Errorf("invalid query: unknown operator %q", opTok.text)
+		}
+	} else if opTok.kind != tokOp {
+		return nil, fmt.Errorf("invalid query: expected operator after %q", field)
+	}
+
+	valTok, err := p.next()
+	if err != nil {
+		return nil, err
+	}
+	if valTok.kind != tokString && valTok.kind != tokWord {
+		return nil, fmt.Errorf("invalid query: expected value after %q", op)
+	}
+
+	c := compare{field: field, op: op, value: valTok.text}
+	switch field {
+	case "name", "mimeType", "fullText":
+		if valTok.kind != tokString {
+			return nil, fmt.Errorf("invalid query: %s requires a quoted string", field)
+		}
+		if field == "fullText" && op != "contains" {
+			return nil, fmt.Errorf("invalid query: fullText only supports 'contains'")
+		}
+	case "modifiedTime", "createdTime", "viewedByMeTime", "sharedWithMeTime":
+		ts, err := parseTime(valTok.text)
+		if err != nil {
+			return nil, fmt.Errorf("invalid query: %s requires an RFC 3339 date, got %q", field, valTok.text)
+		}
+		c.time = ts
+	case "trashed", "starred", "sharedWithMe":
+		b, err := strconv.ParseBool(valTok.text)
+		if err != nil || valTok.kind != tokWord {
+			return nil, fmt.Errorf("invalid query: %s requires true or false", field)
+		}
+		c.bool = b
+	default:
+		return nil, fmt.Errorf("invalid query: unsupported field %q", field)
+	}
+	return c, nil
+}
+
+func (p *parser) parseHas(field string) (Expr, error) {
+	if t, err := p.next(); err != nil || t.kind != tokLBrace {
+		return nil, fmt.Errorf("invalid query: expected '{' after 'has'")
+	}
+	h := has{field: field}
+	for {
+		name, err := p.next()
+		if err != nil {
+			return nil, err
+		}
+		if eq, err := p.next(); err != nil || eq.text != "=" {
+			return nil, fmt.Errorf("invalid query: expected '=' in %s clause", field)
+		}
+		val, err := p.next()
+		if err != nil || val.kind != tokString {
+			return nil, fmt.Errorf("invalid query: expected quoted value in %s clause", field)
+		}
+		switch name.text {
+		case "key":
+			h.key = val.text
+		case "value":
+			h.value = val.text
+		default:
+			return nil, fmt.Errorf("invalid query: unexpected %q in %s clause", name.text, field)
+		}
+		if p.keyword("and") {
+			continue
+		}
+		if t, err := p.next(); err != nil || t.kind != tokRBrace {
+			return nil, fmt.Errorf("invalid query: missing '}'")
+		}
+		return h, nil
+	}
+}
+
+type matchAll struct{}
+
+func (matchAll) Match(*drive.File) bool { return true }
+
+type and struct{ left, right Expr }
+
+func (e and) Match(f *drive.File) bool { return e.left.Match(f) && e.right.Match(f) }
+
+type or struct{ left, right Expr }
+
+func (e or) Match(f *drive.File) bool { return e.left.Match(f) || e.right.Match(f) }
+
+type not struct{ e Expr }
+
+func (e not) Match(f *drive.File) bool { return !e.e.Match(f) }
+
+type in struct {
+	value string
+	field string
+}
+
+func (e in) Match(f *drive.File) bool {
+	switch e.field {
+	case "parents":
+		for _, p := range f.Parents {
+			if p == e.value {
+				return true
+			}
+		}
+	case "owners":
+		for _, o := range f.Owners {
+			if strings.EqualFold(o.EmailAddress, e.value) {
+				return true
+			}
+		}
+	case "writers", "readers":
+		for _, perm := range f.Permissions {
+			if !strings.EqualFold(perm.EmailAddress, e.value) {
+				continue
+			}
+			if e.field == "readers" || perm.Role == "writer" || perm.Role == "owner" || perm.Role == "organizer" {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+type has struct {
+	field string
+	key   string
+	value string
+}
+
+func (e has) Match(f *drive.File) bool {
+	props := f.Properties
+	if e.field == "appProperties" {
+		props = f.AppProperties
+	}
+	v, ok := props[e.key]
+	return ok && v == e.value
+}
+
+type compare struct {
+	field string
+	op    string
+	value string
+	time  time.Time
+	bool  bool
+}
+
+func (e compare) Match(f *drive.File) bool {
+	switch e.field {
+	case "name":
+		return matchString(f.Name, e.op, e.value)
+	case "mimeType":
+		return matchString(f.MimeType, e.op, e.value)
+	case "fullText":
+		v := strings.ToLower(e.value)
+		return strings.Contains(strings.ToLower(f.Name), v) || strings.Contains(strings.ToLower(f.Description), v)
+	case "trashed":
+		return matchBool(f.Trashed, e.op, e.bool)
+	case "starred":
+		return matchBool(f.Starred, e.op, e.bool)
+	case "sharedWithMe":
+		return matchBool(f.SharedWithMeTime != "", e.op, e.bool)
+	case "modifiedTime":
+		return matchTime(f.ModifiedTime, e.op, e.time)
+	case "createdTime":
+		return matchTime(f.CreatedTime, e.op, e.time)
+	case "viewedByMeTime":
+		return matchTime(f.ViewedByMeTime, e.op, e.time)
+	case "sharedWithMeTime":
+		return matchTime(f.SharedWithMeTime, e.op, e.time)
+	}
+	return false
+}
+
+// matchString compares strings the way Drive does: equality is exact and
+// "contains" is a case-insensitive substring match.
+func matchString(actual, op, value string) bool {
+	switch op {
+	case "=":
+		return actual == value
+	case "!=":
+		return actual != value
+	case "contains":
+		return strings.Contains(strings.ToLower(actual), strings.ToLower(value))
+	}
+	return false
+}
+
+func matchBool(actual bool, op string, value bool) bool {
+	switch op {
+	case "=":
+		return actual == value
+	case "!=":
+		return actual != value
+	}
+	return false
+}
+
+// parseTime accepts RFC 3339 timestamps; like Drive, a missing zone means UTC.
+func parseTime(s string) (time.Time, error) {
+	for _, layout := range []string{time.RFC3339, "2006-01-02T15:04:05", "2006-01-02"} {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid time %q", s)
+}
+
+func matchTime(actual, op string, value time.Time) bool {
+	t, err := time.Parse(time.RFC3339, actual)
+	if err != nil {
+		return false
+	}
+	switch op {
+	case "=":
+		return t.Equal(value)
+	case "!=":
+		return !t.Equal(value)
+	case "<":
+		return t.Before(value)
+	case "<=":
+		return !t.After(value)
+	case ">":
+		return t.After(value)
+	case ">=":
+		return !t.Before(value)
+	}
+	return false
+}
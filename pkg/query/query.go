@@ -0,0 +1,181 @@
+// Package query builds and parses Drive v3 search queries (the "q" parameter
+// of Files.List). Building queries through this package escapes every value,
+// so names containing quotes or backslashes cannot break or alter a query.
+package query
+
+import (
+	"strings"
+	"time"
+)
+
+// folderMimeType is the MIME type Drive uses for folders.
+const folderMimeType = "application/vnd.google-apps.folder"
+
+// Query is a Drive search expression. The zero value is an empty query that
+// matches everything and is dropped when combined with And or Or.
+type Query struct {
+	expr     string
+	compound bool
+}
+
+// String returns the query in Drive's search syntax.
+func (q Query) String() string {
+	return q.expr
+}
+
+// IsEmpty reports whether q has no clauses.
+func (q Query) IsEmpty() bool {
+	return q.expr == ""
+}
+
+// And returns q combined with others, all of which must match.
+func (q Query) And(others ...Query) Query {
+	return And(append([]Query{q}, others...)...)
+}
+
+// And returns a query matching items that match every one of qs.
+func And(qs ...Query) Query {
+	return join("and", qs)
+}
+
+// Or returns a query matching items that match any of qs.
+func Or(qs ...Query) Query {
+	return join("or", qs)
+}
+
+// Not negates q.
+func Not(q Query) Query {
+	if q.IsEmpty() {
+		return q
+	}
+	return Query{expr: "not " + q.group()}
+}
+
+// Name matches items named exactly name.
+func Name(name string) Query {
+	return clause("name = " + Quote(name))
+}
+
+// NameContains matches items whose name contains s.
+func NameContains(s string) Query {
+	return clause("name contains " + Quote(s))
+}
+
+// MimeType matches items of exactly the given MIME type.
+func MimeType(mimeType string) Query {
+	return clause("mimeType = " + Quote(mimeType))
+}
+
+// MimeTypeContains matches items whose MIME type contains s, e.g. "image/".
+func MimeTypeContains(s string) Query {
+	return clause("mimeType contains " + Quote(s))
+}
+
+// Folder matches folders.
+func Folder() Query {
+	return MimeType(folderMimeType)
+}
+
+// NotFolder matches everything except folders.
+func NotFolder() Query {
+	return clause("mimeType != " + Quote(folderMimeType))
+}
+
+// InParents matches the direct children of the folder with the given ID.
+func InParents(id string) Query {
+	return clause(Quote(id) + " in parents")
+}
+
+// Trashed matches items whose trashed state equals trashed.
+func Trashed(trashed bool) Query {
+	return clause("trashed = " + boolString(trashed))
+}
+
+// Starred matches items whose starred state equals starred.
+func Starred(starred bool) Query {
+	return clause("starred = " + boolString(starred))
+}
+
+// ModifiedAfter matches items modified strictly after t.
+func ModifiedAfter(t time.Time) Query {
+	return clause("modifiedTime > " + Quote(formatTime(t)))
+}
+
+// ModifiedBefore matches items modified strictly before t.
+func ModifiedBefore(t time.Time) Query {
+	return clause("modifiedTime < " + Quote(formatTime(t)))
+}
+
+// Property matches items with the public custom property key set to value.
+func Property(key, value string) Query {
+	return clause("properties has { key=" + Quote(key) + " and value=" + Quote(value) + " }")
+}
+
+// AppProperty matches items with the private app property key set to value.
+func AppProperty(key, value string) Query {
+	return clause("appProperties has { key=" + Quote(key) + " and value=" + Quote(value) + " }")
+}
+
+// FullText matches items whose name, description or content contains s.
+func FullText(s string) Query {
+	return clause("fullText contains " + Quote(s))
+}
+
+// Owner matches items owned by the user with the given email address.
+func Owner(email string) Query {
+	return clause(Quote(email) + " in owners")
+}
+
+// Quote returns s as a single-quoted Drive query string literal.
+func Quote(s string) string {
+	return "'" + Escape(s) + "'"
+}
+
+// Escape escapes a value for use inside a single-quoted Drive query string.
+func Escape(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(s)
+}
+
+func clause(expr string) Query {
+	return Query{expr: expr}
+}
+
+// group returns the query wrapped in parentheses if it combines clauses.
+func (q Query) group() string {
+	if q.compound {
+		return "(" + q.expr + ")"
+	}
+	return q.expr
+}
+
+func join(op string, qs []Query) Query {
+	var parts []string
+	for _, q := range qs {
+		if !q.IsEmpty() {
+			parts = append(parts, q.group())
+		}
+	}
+	switch len(parts) {
+	case 0:
+		return Query{}
+	case 1:
+		for _, q := range qs {
+			if !q.IsEmpty() {
+				return q
+			}
+		}
+	}
+	return Query{expr: strings.Join(parts, " "+op+" "), compound: true}
+}
+
+func boolString(b bool) string {
+	if b {
+		return "true"
+	}
+	return "false"
+}
+
+// formatTime renders t in the RFC 3339 form Drive expects, in UTC.
+func formatTime(t time.Time) string {
+	return t.UTC().Format(time.RFC3339)
+}
@@ -0,0 +1,143 @@
+package query
+
+import (
+	"testing"
+	"time"
+
+	"google.golang.org/api/drive/v3"
+)
+
+func TestEscape(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"report", "'report'"},
+		{"", "''"},
+		{"Bob's notes", `'Bob\'s notes'`},
+		{`C:\temp`, `'C:\\temp'`},
+		{`\'`, `'\\\''`},
+		{`' or name contains '`, `'\' or name contains \''`},
+	}
+	for _, tt := range tests {
+		if got := Quote(tt.in); got != tt.want {
+			t.Errorf("Quote(%q) = %s, want %s", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestString(t *testing.T) {
+	a, b, c := Name("a"), Name("b"), Name("c")
+	tests := []struct {
+		name string
+		q    Query
+		want string
+	}{
+		{"clause", Name("x"), "name = 'x'"},
+		{"and", And(a, b), "name = 'a' and name = 'b'"},
+		{"or", Or(a, b, c), "name = 'a' or name = 'b' or name = 'c'"},
+		{"method", a.And(b), "name = 'a' and name = 'b'"},
+		{"or inside and", And(Or(a, b), c), "(name = 'a' or name = 'b') and name = 'c'"},
+		{"and inside or", Or(a, And(b, c)), "name = 'a' or (name = 'b' and name = 'c')"},
+		{"nested", And(Or(a, And(b, c)), Trashed(false)), "(name = 'a' or (name = 'b' and name = 'c')) and trashed = false"},
+		{"not clause", Not(a), "not name = 'a'"},
+		{"not group", Not(Or(a, b)), "not (name = 'a' or name = 'b')"},
+		{"not inside and", And(Not(a), b), "not name = 'a' and name = 'b'"},
+		{"empty", Query{}, ""},
+		{"empty and", And(), ""},
+		{"empty dropped", And(Query{}, a, Query{}), "name = 'a'"},
+		{"empty dropped from or", Or(a, Query{}, b), "name = 'a' or name = 'b'"},
+		{"only empties", Or(Query{}, Query{}), ""},
+		{"single group kept", And(Query{}, Or(a, b)), "name = 'a' or name = 'b'"},
+		{"single group regrouped", And(And(Query{}, Or(a, b)), c), "(name = 'a' or name = 'b') and name = 'c'"},
+		{"not empty", Not(Query{}), ""},
+		{"in parents", InParents("1AbC"), "'1AbC' in parents"},
+		{"owner", Owner("me@example.com"), "'me@example.com' in owners"},
+		{"property", Property("k", "v'"), `properties has { key='k' and value='v\'' }`},
+		{"modified after", ModifiedAfter(time.Date(2024, 3, 1, 12, 0, 0, 0, time.FixedZone("", 3600))), "modifiedTime > '2024-03-01T11:00:00Z'"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.q.String(); got != tt.want {
+				t.Errorf("got %s, want %s", got, tt.want)
+			}
+			if tt.q.IsEmpty() != (tt.want == "") {
+				t.Errorf("IsEmpty() = %v for %q", tt.q.IsEmpty(), tt.want)
+			}
+		})
+	}
+}
+
+// TestRoundTrip builds queries, parses their String form and checks which
+// files they match, so the builder and the parser agree on the grammar.
+func TestRoundTrip(t *testing.T) {
+	at := func(s string) string { return s + "T12:00:00Z" }
+	files := map[string]*drive.File{
+		"report": {Name: "Q1 report.pdf", MimeType: "application/pdf", Parents: []string{"root"}, ModifiedTime: at("2024-03-10")},
+		"quote":  {Name: "Bob's notes", MimeType: "text/plain", Parents: []string{"root"}, Starred: true, ModifiedTime: at("2024-01-05")},
+		"slash":  {Name: `C:\temp\log`, MimeType: "text/plain", Parents: []string{"folder"}, ModifiedTime: at("2024-02-01")},
+		"folder": {Name: "Projects", MimeType: folderMimeType, Parents: []string{"root"}, ModifiedTime: at("2023-12-31")},
+		"trash": {Name: "old report.pdf", MimeType: "application/pdf", Parents: []string{"folder"}, Trashed: true,
+			Owners: []*drive.User{{EmailAddress: "me@example.com"}}, Properties: map[string]string{"team": "ops"}, ModifiedTime: at("2022-06-01")},
+	}
+	tests := []struct {
+		name string
+		q    Query
+		want []string
+	}{
+		{"empty", Query{}, []string{"report", "quote", "slash", "folder", "trash"}},
+		{"exact name with quote", Name("Bob's notes"), []string{"quote"}},
+		{"name with backslashes", Name(`C:\temp\log`), []string{"slash"}},
+		{"contains is case insensitive", NameContains("REPORT"), []string{"report", "trash"}},
+		{"contains quote", NameContains("'s"), []string{"quote"}},
+		{"injection stays a literal", NameContains("' or name contains '"), nil},
+		{"folders", Folder(), []string{"folder"}},
+		{"not folders", And(NotFolder(), Trashed(false)), []string{"report", "quote", "slash"}},
+		{"mime type prefix", MimeTypeContains("text/"), []string{"quote", "slash"}},
+		{"in parents", And(InParents("root"), Trashed(false)), []string{"report", "quote", "folder"}},
+		{"or then and", And(Or(NameContains("report"), Starred(true)), Trashed(false)), []string{"report", "quote"}},
+		{"and then or", Or(And(NameContains("report"), Trashed(true)), Folder()), []string{"trash", "folder"}},
+		{"not group", And(Not(Or(Folder(), Trashed(true))), InParents("root")), []string{"report", "quote"}},
+		{"empty parts dropped", And(Query{}, Starred(true), Or()), []string{"quote"}},
+		{"modified range", And(ModifiedAfter(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)), ModifiedBefore(time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC))), []string{"quote", "slash"}},
+		{"owner", Owner("ME@example.com"), []string{"trash"}},
+		{"property", Property("team", "ops"), []string{"trash"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			expr, err := Parse(tt.q.String())
+			if err != nil {
+				t.Fatalf("Parse(%s): %v", tt.q, err)
+			}
+			want := make(map[string]bool)
+			for _, name := range tt.want {
+				want[name] = true
+			}
+			for name, f := range files {
+				if got := expr.Match(f); got != want[name] {
+					t.Errorf("%s matches %s = %v, want %v", tt.q, name, got, want[name])
+				}
+			}
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, q := range []string{
+		"name = 'unterminated",
+		"name = unquoted",
+		"(name = 'a'",
+		"name = 'a')",
+		"name = 'a' and",
+		"size > 10",
+		"trashed = maybe",
+		"modifiedTime > 'yesterday'",
+		"'x' in name",
+		"fullText = 'x'",
+		"properties has { key='k' value='v' }",
+	} {
+		if _, err := Parse(q); err == nil {
+			t.Errorf("Parse(%q) succeeded, want an error", q)
+		}
+	}
+}
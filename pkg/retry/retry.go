@@ -0,0 +1,151 @@
+package retry
+
+import (
+	"errors"
+	"io"
+	"log"
+	"math/rand"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"syscall"
+	"time"
+
+	"google.golang.org/api/googleapi"
+)
+
+// Policy controls how many times a call is attempted and how long to wait between attempts.
+type Policy struct {
+	MaxAttempts int
+	BaseDelay   time.Duration
+	MaxDelay    time.Duration
+
+	// Sleep waits between attempts; it can be replaced to keep tests fast.
+	Sleep func(time.Duration)
+}
+
+// DefaultPolicy returns the policy used by drivebox commands. The number of
+// attempts can be changed with DRIVEBOX_MAX_RETRIES in the .env config.
+func DefaultPolicy() Policy {
+	p := Policy{
+		MaxAttempts: 5,
+		BaseDelay:   500 * time.Millisecond,
+		MaxDelay:    32 * time.Second,
+		Sleep:       time.Sleep,
+	}
+	if v := os.Getenv("DRIVEBOX_MAX_RETRIES"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n >= 0 {
+			p.MaxAttempts = n + 1
+		} else {
+			log.Printf("Ignoring invalid DRIVEBOX_MAX_RETRIES value %q", v)
+		}
+	}
+	return p
+}
+
+// Do calls fn with the default policy.
+func Do(fn func() error) error {
+	return DefaultPolicy().Do(fn)
+}
+
+// Call calls fn with the default policy and returns its result.
+func Call[T any](fn func() (T, error)) (T, error) {
+	var res T
+	err := DefaultPolicy().Do(func() error {
+		var err error
+		res, err = fn()
+		return err
+	})
+	return res, err
+}
+
+// Do calls fn until it succeeds, fails with an error that is not worth
+// retrying, or the policy runs out of attempts. The last error is returned.
+func (p Policy) Do(fn func() error) error {
+	sleep := p.Sleep
+	if sleep == nil {
+		sleep = time.Sleep
+	}
+
+	var err error
+	for attempt := 1; ; attempt++ {
+		err = fn()
+		if err == nil {
+			return nil
+		}
+		retryable, after := Classify(err)
+		if !retryable || attempt >= p.MaxAttempts {
+			return err
+		}
+
+		delay := p.backoff(attempt)
+		if after > delay {
+			delay = after
+		}
+		log.Printf("Request failed (attempt %d of %d), retrying in %s: %v", attempt, p.MaxAttempts, delay.Round(time.Millisecond), err)
+		sleep(delay)
+	}
+}
+
+// backoff returns a jittered exponential delay for the given attempt, using
+// the "full jitter" strategy: a random duration up to the capped exponential.
+func (p Policy) backoff(attempt int) time.Duration {
+	ceiling := p.BaseDelay << (attempt - 1)
+	if ceiling <= 0 || ceiling > p.MaxDelay {
+		ceiling = p.MaxDelay
+	}
+	if ceiling <= 0 {
+		return 0
+	}
+	return time.Duration(rand.Int63n(int64(ceiling))) + time.Millisecond
+}
+
+// Classify reports whether err is transient and, if the server asked for it
+// via Retry-After, how long to wait before trying again.
+func Classify(err error) (retryable bool, after time.Duration) {
+	var apiErr *googleapi.Error
+	if errors.As(err, &apiErr) {
+		after = retryAfter(apiErr.Header)
+		switch {
+		case apiErr.Code == http.StatusTooManyRequests:
+			return true, after
+		case apiErr.Code >= 500:
+			return true, after
+		case apiErr.Code == http.StatusForbidden:
+			for _, e := range apiErr.Errors {
+				if e.Reason == "rateLimitExceeded" || e.Reason == "userRateLimitExceeded" {
+					return true, after
+				}
+			}
+		}
+		return false, 0
+	}
+
+	if errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.ECONNREFUSED) ||
+		errors.Is(err, io.ErrUnexpectedEOF) {
+		return true, 0
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true, 0
+	}
+	return false, 0
+}
+
+// retryAfter parses a Retry-After header given either in seconds or as an HTTP date.
+func retryAfter(h http.Header) time.Duration {
+	v := h.Get("Retry-After")
+	if v == "" {
+		return 0
+	}
+	if secs, err := strconv.Atoi(v); err == nil && secs > 0 {
+		return time.Duration(secs) * time.Second
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		if d := time.Until(t); d > 0 {
+			return d
+		}
+	}
+	return 0
+}
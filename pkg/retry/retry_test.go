@@ -0,0 +1,179 @@
+package retry
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"google.golang.org/api/googleapi"
+)
+
+// response is one canned reply from the test server.
+type response struct {
+	status     int
+	reason     string
+	retryAfter string
+}
+
+// server replies to successive requests with responses, then with 200 OK.
+// It returns a function that makes one request and turns the reply into an
+// error the way the generated Drive client does.
+func server(t *testing.T, responses ...response) (call func() error, requests *int) {
+	t.Helper()
+	var n int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n++
+		if n > len(responses) {
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+		res := responses[n-1]
+		if res.retryAfter != "" {
+			w.Header().Set("Retry-After", res.retryAfter)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(res.status)
+		fmt.Fprintf(w, `{"error": {"code": %d, "message": "test", "errors": [{"reason": %q}]}}`, res.status, res.reason)
+	}))
+	t.Cleanup(srv.Close)
+
+	return func() error {
+		resp, err := http.Get(srv.URL)
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+		return googleapi.CheckResponse(resp)
+	}, &n
+}
+
+// testPolicy records the delays it would have slept for instead of sleeping.
+func testPolicy(attempts int, slept *[]time.Duration) Policy {
+	return Policy{
+		MaxAttempts: attempts,
+		BaseDelay:   10 * time.Millisecond,
+		MaxDelay:    time.Second,
+		Sleep:       func(d time.Duration) { *slept = append(*slept, d) },
+	}
+}
+
+func TestDoRetries(t *testing.T) {
+	tests := []struct {
+		name      string
+		responses []response
+		attempts  int
+		wantCalls int
+		wantErr   int
+	}{
+		{"too many requests", []response{{status: 429}}, 5, 2, 0},
+		{"rate limit exceeded", []response{{status: 403, reason: "rateLimitExceeded"}}, 5, 2, 0},
+		{"user rate limit exceeded", []response{{status: 403, reason: "userRateLimitExceeded"}}, 5, 2, 0},
+		{"server errors", []response{{status: 500}, {status: 502}, {status: 503}}, 5, 4, 0},
+		{"forbidden", []response{{status: 403, reason: "insufficientFilePermissions"}}, 5, 1, 403},
+		{"not found", []response{{status: 404, reason: "notFound"}}, 5, 1, 404},
+		{"attempts exhausted", []response{{status: 503}, {status: 503}, {status: 503}}, 3, 3, 503},
+		{"no retries", []response{{status: 503}}, 1, 1, 503},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			call, requests := server(t, tt.responses...)
+			var slept []time.Duration
+			err := testPolicy(tt.attempts, &slept).Do(call)
+
+			if *requests != tt.wantCalls {
+				t.Errorf("made %d requests, want %d", *requests, tt.wantCalls)
+			}
+			if len(slept) != tt.wantCalls-1 {
+				t.Errorf("slept %d times, want %d", len(slept), tt.wantCalls-1)
+			}
+			var apiErr *googleapi.Error
+			switch {
+			case tt.wantErr == 0 && err != nil:
+				t.Errorf("got error %v, want success", err)
+			case tt.wantErr != 0 && (!errors.As(err, &apiErr) || apiErr.Code != tt.wantErr):
+				t.Errorf("got error %v, want HTTP %d", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestDoHonoursRetryAfter(t *testing.T) {
+	call, requests := server(t, response{status: 429, retryAfter: "7"})
+	var slept []time.Duration
+	if err := testPolicy(3, &slept).Do(call); err != nil {
+		t.Fatalf("Do: %v", err)
+	}
+	if *requests != 2 {
+		t.Errorf("made %d requests, want 2", *requests)
+	}
+	if len(slept) != 1 || slept[0] != 7*time.Second {
+		t.Errorf("slept %v, want [7s]", slept)
+	}
+}
+
+func TestBackoffIsCapped(t *testing.T) {
+	p := Policy{BaseDelay: 100 * time.Millisecond, MaxDelay: time.Second}
+	for attempt := 1; attempt <= 20; attempt++ {
+		if d := p.backoff(attempt); d <= 0 || d > p.MaxDelay+time.Millisecond {
+			t.Errorf("backoff(%d) = %s, want within (0, %s]", attempt, d, p.MaxDelay)
+		}
+	}
+}
+
+func TestClassify(t *testing.T) {
+	tests := []struct {
+		name      string
+		err       error
+		retryable bool
+		after     time.Duration
+	}{
+		{"429", &googleapi.Error{Code: 429}, true, 0},
+		{"500", &googleapi.Error{Code: 500}, true, 0},
+		{"403 rate limit", &googleapi.Error{Code: 403, Errors: []googleapi.ErrorItem{{Reason: "rateLimitExceeded"}}}, true, 0},
+		{"403 user rate limit", &googleapi.Error{Code: 403, Errors: []googleapi.ErrorItem{{Reason: "userRateLimitExceeded"}}}, true, 0},
+		{"403 other", &googleapi.Error{Code: 403, Errors: []googleapi.ErrorItem{{Reason: "forbidden"}}}, false, 0},
+		{"404", &googleapi.Error{Code: 404}, false, 0},
+		{"400", &googleapi.Error{Code: 400}, false, 0},
+		{"retry after", &googleapi.Error{Code: 503, Header: http.Header{"Retry-After": {"3"}}}, true, 3 * time.Second},
+		{"wrapped", fmt.Errorf("upload: %w", &googleapi.Error{Code: 502}), true, 0},
+		{"other error", errors.New("boom"), false, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			retryable, after := Classify(tt.err)
+			if retryable != tt.retryable || after != tt.after {
+				t.Errorf("Classify = (%v, %s), want (%v, %s)", retryable, after, tt.retryable, tt.after)
+			}
+		})
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	future := time.Now().Add(time.Hour).UTC().Format(http.TimeFormat)
+	past := time.Now().Add(-time.Hour).UTC().Format(http.TimeFormat)
+	tests := []struct {
+		value string
+		min   time.Duration
+		max   time.Duration
+	}{
+		{"", 0, 0},
+		{"5", 5 * time.Second, 5 * time.Second},
+		{"0", 0, 0},
+		{"-1", 0, 0},
+		{"soon", 0, 0},
+		{past, 0, 0},
+		{future, 59 * time.Minute, time.Hour},
+	}
+	for _, tt := range tests {
+		h := http.Header{}
+		if tt.value != "" {
+			h.Set("Retry-After", tt.value)
+		}
+		if d := retryAfter(h); d < tt.min || d > tt.max {
+			t.Errorf("retryAfter(%q) = %s, want between %s and %s", tt.value, d, tt.min, tt.max)
+		}
+	}
+}
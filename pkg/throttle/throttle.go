@@ -0,0 +1,154 @@
+package throttle
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Upload and Download are the process-wide limiters shared by every transfer
+// in that direction, so concurrent transfers respect the combined rate.
+var (
+	Upload   = &Limiter{}
+	Download = &Limiter{}
+)
+
+// maxChunk bounds how many bytes a single read may consume so that a large
+// buffer does not produce one long stall followed by a burst.
+const maxChunk = 32 * 1024
+
+// Limiter is a token bucket measured in bytes per second. The zero value does
+// not limit. It implements pflag.Value so it can be bound directly to a flag.
+type Limiter struct {
+	mu     sync.Mutex
+	rate   int64
+	tokens float64
+	last   time.Time
+}
+
+// SetRate changes the limit to bytesPerSec; zero disables limiting.
+func (l *Limiter) SetRate(bytesPerSec int64) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.rate = bytesPerSec
+	l.tokens = 0
+	l.last = time.Now()
+}
+
+// Rate returns the current limit in bytes per second.
+func (l *Limiter) Rate() int64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.rate
+}
+
+// Reader wraps r so that reads from it are paced to the limiter's rate.
+// If no limit is set r is returned unchanged.
+func (l *Limiter) Reader(r io.Reader) io.Reader {
+	if l.Rate() <= 0 {
+		return r
+	}
+	return &reader{r: r, l: l}
+}
+
+// wait blocks until n bytes may be transferred. Tokens are reserved up front,
+// so waiting goroutines are served in the order they asked.
+func (l *Limiter) wait(n int) {
+	l.mu.Lock()
+	if l.rate <= 0 {
+		l.mu.Unlock()
+		return
+	}
+	now := time.Now()
+	l.tokens += now.Sub(l.last).Seconds() * float64(l.rate)
+	if burst := float64(l.rate); l.tokens > burst {
+		l.tokens = burst
+	}
+	l.last = now
+	l.tokens -= float64(n)
+	var delay time.Duration
+	if l.tokens < 0 {
+		delay = time.Duration(-l.tokens / float64(l.rate) * float64(time.Second))
+	}
+	l.mu.Unlock()
+
+	if delay > 0 {
+		time.Sleep(delay)
+	}
+}
+
+type reader struct {
+	r io.Reader
+	l *Limiter
+}
+
+func (r *reader) Read(p []byte) (int, error) {
+	if len(p) > maxChunk {
+		p = p[:maxChunk]
+	}
+	n, err := r.r.Read(p)
+	if n > 0 {
+		r.l.wait(n)
+	}
+	return n, err
+}
+
+// String implements pflag.Value.
+func (l *Limiter) String() string {
+	rate := l.Rate()
+	if rate <= 0 {
+		return ""
+	}
+	return strconv.FormatInt(rate, 10) + "B/s"
+}
+
+// Set implements pflag.Value by parsing a rate such as "5MB/s".
+func (l *Limiter) Set(s string) error {
+	rate, err := ParseRate(s)
+	if err != nil {
+		return err
+	}
+	l.SetRate(rate)
+	return nil
+}
+
+// Type implements pflag.Value.
+func (l *Limiter) Type() string {
+	return "rate"
+}
+
+// ParseRate parses a transfer rate like "5MB/s", "500K" or "1MiB/s" into bytes
+// per second. Decimal suffixes (KB, MB, GB) use powers of 1000 and binary
+// suffixes (KiB, MiB, GiB) use powers of 1024. A bare number is bytes.
+func ParseRate(s string) (int64, error) {
+	v := strings.TrimSpace(s)
+	v = strings.TrimSuffix(strings.TrimSuffix(v, "/s"), "ps")
+	upper := strings.ToUpper(v)
+
+	multipliers := []struct {
+		suffix string
+		mult   int64
+	}{
+		{"KIB", 1 << 10}, {"MIB", 1 << 20}, {"GIB", 1 << 30},
+		{"KB", 1e3}, {"MB", 1e6}, {"GB", 1e9},
+		{"K", 1e3}, {"M", 1e6}, {"G", 1e9},
+		{"B", 1},
+	}
+	mult := int64(1)
+	for _, m := range multipliers {
+		if strings.HasSuffix(upper, m.suffix) {
+			mult = m.mult
+			v = strings.TrimSpace(v[:len(v)-len(m.suffix)])
+			break
+		}
+	}
+
+	n, err := strconv.ParseFloat(v, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid rate %q: expected a value like 5MB/s", s)
+	}
+	return int64(n * float64(mult)), nil
+}
@@ -0,0 +1,99 @@
+package throttle
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseRate(t *testing.T) {
+	tests := []struct {
+		in   string
+		want int64
+	}{
+		{"0", 0},
+		{"500", 500},
+		{"500B/s", 500},
+		{"500K", 500e3},
+		{"5MB/s", 5e6},
+		{"5mb/s", 5e6},
+		{"1.5MB", 1.5e6},
+		{"2GB/s", 2e9},
+		{"1KiB/s", 1 << 10},
+		{"1MiB", 1 << 20},
+		{"1GiB/s", 1 << 30},
+		{"8Mbps", 8e6},
+		{" 10 MB/s ", 10e6},
+	}
+	for _, tt := range tests {
+		got, err := ParseRate(tt.in)
+		if err != nil {
+			t.Errorf("ParseRate(%q): %v", tt.in, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseRate(%q) = %d, want %d", tt.in, got, tt.want)
+		}
+	}
+
+	for _, in := range []string{"", "fast", "-5MB/s", "MB/s", "5XB/s"} {
+		if _, err := ParseRate(in); err == nil {
+			t.Errorf("ParseRate(%q) succeeded, want an error", in)
+		}
+	}
+}
+
+func TestFlagValue(t *testing.T) {
+	var l Limiter
+	if l.Type() != "rate" {
+		t.Errorf("Type() = %q, want rate", l.Type())
+	}
+	if l.String() != "" {
+		t.Errorf("unset limiter prints %q, want an empty string", l.String())
+	}
+	if err := l.Set("2MB/s"); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+	if l.Rate() != 2e6 || l.String() != "2000000B/s" {
+		t.Errorf("after Set(2MB/s) rate is %d, printed %q", l.Rate(), l.String())
+	}
+
+	// The printed value must parse back to the same rate
+	var round Limiter
+	if err := round.Set(l.String()); err != nil || round.Rate() != l.Rate() {
+		t.Errorf("Set(%q) gave %d (%v), want %d", l.String(), round.Rate(), err, l.Rate())
+	}
+
+	if err := l.Set("soon"); err == nil {
+		t.Error("Set accepted an invalid rate")
+	}
+	if l.Rate() != 2e6 {
+		t.Errorf("a rejected Set changed the rate to %d", l.Rate())
+	}
+}
+
+func TestReader(t *testing.T) {
+	var unlimited Limiter
+	r := strings.NewReader("data")
+	if unlimited.Reader(r) != io.Reader(r) {
+		t.Error("a limiter without a rate should return the reader unchanged")
+	}
+
+	var l Limiter
+	l.SetRate(10e6)
+	data := bytes.Repeat([]byte("x"), 2e6)
+	start := time.Now()
+	got, err := io.ReadAll(l.Reader(bytes.NewReader(data)))
+	if err != nil {
+		t.Fatalf("ReadAll: %v", err)
+	}
+	if !bytes.Equal(got, data) {
+		t.Fatalf("read %d bytes that differ from the %d written", len(got), len(data))
+	}
+	// 2 MB at 10 MB/s, starting with an empty bucket, takes about 200ms
+	if elapsed := time.Since(start); elapsed < 150*time.Millisecond {
+		t.Errorf("reading 2 MB at 10 MB/s took %s, want at least 150ms", elapsed)
+	}
+}